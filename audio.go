@@ -5,18 +5,17 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/gob"
-	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/rylio/ytdl"
+	"github.com/bwmarrin/discordgo"
 	"layeh.com/gopus"
 )
 
@@ -30,9 +29,24 @@ const (
 	frameRate int = 48000               // audio sampling rate
 	frameSize int = 960                 // uint16 size of each audio frame
 	maxBytes  int = (frameSize * 2) * 2 // max size of opus data
+
+	// maxClipFrames is the most opus frames we'll convert for a single clip
+	maxClipFrames = maxClipSeconds * int(time.Second/frameDuration)
+	// ffmpegStderrTail is how much of ffmpeg's output is kept to explain a failed conversion
+	ffmpegStderrTail = 1024
 )
 
+// soundDir is the local directory sounds are stored in when S3 persistence is off
+const soundDir = "sounds"
+
+// uploadExtensions are the attachment types $upload will accept
+var uploadExtensions = []string{".mp3", ".wav", ".ogg", ".m4a"}
+
 var s3Persistence string = os.Getenv("S3_PERSISTENCE")
+
+// soundStore is where all sound clips are persisted. It's selected once in Start.
+var soundStore SoundStore
+
 var cache = struct {
 	sync.RWMutex
 	m map[string][][]byte
@@ -51,99 +65,354 @@ func putCache(name string, data [][]byte) {
 	cache.Unlock()
 }
 
+func evictCache(name string) {
+	cache.Lock()
+	delete(cache.m, name)
+	cache.Unlock()
+}
+
 // TODO: Commands maybe should be moved into their own file and solely audio utility functions live here
-func playSound(playCmd playCommand) ([][]byte, error) {
-	val, ok := checkCache(playCmd.name)
+// playSound loads the sound the play command most likely meant, returning its name and audio.
+func playSound(playCmd playCommand) (string, [][]byte, error) {
+	name, err := resolveSoundName(playCmd.name)
+	if err != nil {
+		return "", nil, err
+	}
+	audio, err := loadSound(name)
+	return name, audio, err
+}
+
+// resolveSoundName returns the sound the name refers to, fuzzy matching it against every sound
+// unless it's already cached.
+func resolveSoundName(name string) (string, error) {
+	if _, ok := checkCache(name); ok {
+		return name, nil
+	}
+	sounds, err := soundStore.List()
+	if err != nil {
+		return "", retryableError(codeStorage, "Error retrieving sound", err)
+	}
+
+	match, suggestions := matchSound(sounds, name)
+	if match != "" {
+		return match, nil
+	}
+	if len(suggestions) > 0 {
+		return "", newUserError(codeNotFound, fmt.Sprintf("Couldn't find %v. Did you mean %v?", name, strings.Join(suggestions, ", ")))
+	}
+	return "", newUserError(codeNotFound, "No sound matches "+name)
+}
+
+// loadSound returns the opus frames of the named sound, checking the cache before the store.
+func loadSound(name string) ([][]byte, error) {
+	val, ok := checkCache(name)
 	if ok {
 		return val, nil
 	}
 
-	var opusData []byte
-	if s3Persistence == "true" {
-		opusData = getSoundS3(playCmd.name)
-	} else {
-		opusData = getSoundLocal(playCmd.name)
+	opusData, err := soundStore.Get(name)
+	if err == errNotFound {
+		return nil, newUserError(codeNotFound, "Sound not found")
+	}
+	if err != nil {
+		return nil, retryableError(codeStorage, "Error retrieving sound", err)
 	}
 
-	decodedFrames := gobDecodeOpusFrames(opusData)
-	putCache(playCmd.name, decodedFrames)
+	decodedFrames, err := decodeClip(opusData)
+	if err != nil {
+		return nil, wrapError(codeStorage, "That sound is corrupt or unreadable", fmt.Errorf("decoding %v: %v", name, err))
+	}
+	putCache(name, decodedFrames)
 	return decodedFrames, nil
 }
 
 func listSounds(listCmd listCommand) (string, error) {
-	var (
-		err    error
-		sounds []string
-	)
-	if s3Persistence == "true" {
-		sounds, err = listSoundsS3()
-	} else {
-		sounds, err = listSoundsLocal()
+	if listCmd.tag != "" {
+		return listTaggedSounds(listCmd.tag)
 	}
-
+	sounds, err := soundStore.List()
 	if err != nil {
-		return "", err
+		return "", retryableError(codeStorage, "Unable to list sounds", err)
 	}
 
 	return "Available Sounds: " + strings.Join(sounds, ", "), nil
 }
 
+func listTaggedSounds(tag string) (string, error) {
+	records, err := allMetadata()
+	if err != nil {
+		return "", retryableError(codeStorage, "Unable to list sounds", err)
+	}
+	sounds := make([]string, 0)
+	for _, meta := range records {
+		if meta.hasTag(tag) {
+			sounds = append(sounds, meta.Name)
+		}
+	}
+	if len(sounds) == 0 {
+		return "No sounds are tagged " + tag, nil
+	}
+	return "Sounds tagged " + tag + ": " + strings.Join(sounds, ", "), nil
+}
+
+// searchSounds finds sounds whose name matches the query like $play would, followed by sounds
+// with a tag containing it.
+func searchSounds(searchCmd searchCommand) (string, error) {
+	sounds, err := soundStore.List()
+	if err != nil {
+		return "", retryableError(codeStorage, "Unable to search sounds", err)
+	}
+	records, err := allMetadata()
+	if err != nil {
+		return "", retryableError(codeStorage, "Unable to search sounds", err)
+	}
+
+	found := make([]string, 0)
+	for _, match := range rankSounds(sounds, searchCmd.query) {
+		found = append(found, match.name)
+	}
+	query := strings.ToLower(searchCmd.query)
+	for _, meta := range records {
+		if containsString(found, meta.Name) {
+			continue
+		}
+		for _, tag := range meta.Tags {
+			if strings.Contains(tag, query) {
+				found = append(found, meta.Name)
+				break
+			}
+		}
+	}
+	if len(found) == 0 {
+		return "Nothing matches " + searchCmd.query, nil
+	}
+	return "Sounds matching " + searchCmd.query + ": " + strings.Join(found, ", "), nil
+}
+
+func deleteSound(deleteCmd deleteCommand) error {
+	err := soundStore.Delete(deleteCmd.name)
+	if err == errNotFound {
+		return newUserError(codeNotFound, "Sound not found")
+	}
+	if err != nil {
+		return retryableError(codeStorage, "Error deleting sound", err)
+	}
+	evictCache(deleteCmd.name)
+
+	err = deleteMetadata(deleteCmd.name)
+	if err != nil {
+		log.Println("Failed to delete metadata: ", err)
+	}
+	return nil
+}
+
+func renameSound(renameCmd renameCommand) error {
+	exists, err := soundStore.Exists(renameCmd.newName)
+	if err != nil {
+		return retryableError(codeStorage, "Error renaming sound", err)
+	}
+	if exists {
+		return newUserError(codeConflict, "A sound with that name already exists")
+	}
+
+	data, err := soundStore.Get(renameCmd.oldName)
+	if err == errNotFound {
+		return newUserError(codeNotFound, "Sound not found")
+	}
+	if err != nil {
+		return retryableError(codeStorage, "Error renaming sound", err)
+	}
+
+	err = soundStore.Put(renameCmd.newName, data)
+	if err != nil {
+		return retryableError(codeStorage, "Error renaming sound", err)
+	}
+	err = soundStore.Delete(renameCmd.oldName)
+	if err != nil {
+		return retryableError(codeStorage, "Error renaming sound", fmt.Errorf("deleting old sound: %v", err))
+	}
+	evictCache(renameCmd.oldName)
+
+	err = renameMetadata(renameCmd.oldName, renameCmd.newName)
+	if err != nil {
+		log.Println("Failed to rename metadata: ", err)
+	}
+	return nil
+}
+
 // TODO: The code duplication here for error handling is unreal.
 // Consider adding functionality to the functions so they return instantly
 // if passed a nil value so we can a single error check at the end.
-func ripSound(ripCmd ripCommand) error {
-	videoBuf, err := fetchVideoData(ripCmd.url)
+func ripSound(ripCmd ripCommand, author *discordgo.User) error {
+	duration, _ := strconv.ParseFloat(ripCmd.duration, 64)
+	opusFrames, err := ripMedia(ripCmd.url, ripCmd.start, ripCmd.duration, ripCmd.effects.filter(duration))
 	if err != nil {
 		return err
 	}
-	opusFrames, err := convertToOpusFrames(videoBuf, ripCmd.start, ripCmd.duration)
+	return saveSound(opusFrames, newRipMetadata(ripCmd, author, len(opusFrames)))
+}
+
+// ensureNameFree returns a conflict error if there's already a sound with the name.
+func ensureNameFree(name string) error {
+	exists, err := soundStore.Exists(name)
+	if err != nil {
+		return retryableError(codeStorage, "Error checking sound name", err)
+	}
+	if exists {
+		return newUserError(codeConflict, "A sound with that name already exists")
+	}
+	return nil
+}
+
+// saveSound persists the opus frames and metadata of a newly created sound under meta.Name.
+func saveSound(opusFrames [][]byte, meta clipMetadata) error {
+	encodedFrames, err := encodeClip(opusFrames)
+	if err != nil {
+		return wrapError(codeMedia, "Error encoding audio", err)
+	}
+
+	err = soundStore.Put(meta.Name, encodedFrames)
+	if err != nil {
+		return retryableError(codeStorage, "Error saving sound", err)
+	}
+	evictCache(meta.Name)
+
+	err = putMetadata(meta)
+	if err != nil {
+		log.Println("Failed to save metadata: ", err)
+	}
+	return nil
+}
+
+func uploadSound(uploadCmd uploadCommand, m *discordgo.Message) error {
+	err := ensureNameFree(uploadCmd.name)
 	if err != nil {
 		return err
 	}
-	encodedFrames, err := gobEncodeOpusFrames(opusFrames)
+	att := findAttachment(m, uploadExtensions...)
+	if att == nil {
+		return invalidInput("Attach an audio file (" + strings.Join(uploadExtensions, ", ") + ") to upload")
+	}
+	data, err := downloadAttachment(att)
+	if err != nil {
+		return err
+	}
+
+	opusFrames, err := convertToOpusFrames(bytes.NewBuffer(data), uploadCmd.start, uploadCmd.duration, "")
 	if err != nil {
 		return err
 	}
+	if len(opusFrames) == 0 {
+		return invalidInput("That file doesn't contain any audio")
+	}
 
-	if s3Persistence == "true" {
-		err = putSoundS3(encodedFrames, ripCmd.name)
-	} else {
-		err = putSoundLocal(encodedFrames, ripCmd.name)
+	start, _ := strconv.Atoi(uploadCmd.start)
+	meta := clipMetadata{
+		Name:           uploadCmd.name,
+		SourceURL:      att.URL,
+		Start:          start,
+		End:            start + int((time.Duration(len(opusFrames)) * frameDuration).Seconds()),
+		DurationFrames: len(opusFrames),
+		CreatedBy:      m.Author.Username,
+		CreatedByID:    m.Author.ID,
+		CreatedAt:      time.Now().UTC(),
 	}
-	return err
+	return saveSound(opusFrames, meta)
 }
 
-func fetchVideoData(url string) (*bytes.Buffer, error) {
-	vid, err := ytdl.GetVideoInfo(url)
+func exportSound(exportCmd exportCommand) (*discordgo.File, error) {
+	opusFrames, err := loadSound(exportCmd.name)
+	if err != nil {
+		return nil, err
+	}
+	ogg, err := muxOggOpus(opusFrames)
 	if err != nil {
-		return nil, errors.New("Failed to get video info. Is the url valid?")
+		return nil, wrapError(codeMedia, "Error exporting sound", fmt.Errorf("muxing %v: %v", exportCmd.name, err))
+	}
+	return &discordgo.File{
+		Name:        exportCmd.name + ".ogg",
+		ContentType: "audio/ogg",
+		Reader:      bytes.NewReader(ogg),
+	}, nil
+}
+
+func importSound(importCmd importCommand, m *discordgo.Message) error {
+	err := ensureNameFree(importCmd.name)
+	if err != nil {
+		return err
+	}
+	att := findAttachment(m, ".ogg", ".opus")
+	if att == nil {
+		return invalidInput("Attach an .ogg file to import")
+	}
+	data, err := downloadAttachment(att)
+	if err != nil {
+		return err
 	}
 
-	buf := new(bytes.Buffer)
-	err = vid.Download(vid.Formats[0], buf)
-	// err = vid.Download(vid.Formats.Best(ytdl.FormatResolutionKey)[0], buf)
+	info, packets, err := demuxOggOpus(data)
 	if err != nil {
-		return nil, errors.New("Error downloading video")
+		return wrapError(codeInvalidInput, "That doesn't look like an Ogg/Opus file", fmt.Errorf("demuxing %v: %v", att.Filename, err))
+	}
+	// Anything we can't hand to Discord as is gets transcoded into 20ms stereo frames
+	if !isPlayableOpus(info, packets) {
+		packets, err = convertToOpusFrames(bytes.NewBuffer(data), "", "", "")
+		if err != nil {
+			return err
+		}
+	}
+	if len(packets) == 0 {
+		return invalidInput("That file doesn't contain any audio")
+	}
+
+	meta := clipMetadata{
+		Name:           importCmd.name,
+		SourceURL:      att.URL,
+		DurationFrames: len(packets),
+		CreatedBy:      m.Author.Username,
+		CreatedByID:    m.Author.ID,
+		CreatedAt:      time.Now().UTC(),
+	}
+	return saveSound(packets, meta)
+}
+
+// ffmpegArgs builds the arguments converting stdin to raw PCM on stdout. An empty start or
+// duration means convert the whole thing. Both go before -i so they limit the input: ffmpeg
+// skips decoding everything up to start, quits reading once the clip is done and the filters
+// only ever see the requested clip.
+func ffmpegArgs(start, duration, audioFilter string) []string {
+	args := make([]string, 0)
+	if start != "" {
+		args = append(args, "-ss", start)
+	}
+	if duration != "" {
+		args = append(args, "-t", duration)
+	}
+	args = append(args, "-i", "pipe:0")
+	if audioFilter != "" {
+		args = append(args, "-af", audioFilter)
 	}
-	return buf, nil
+	return append(args, "-f", "s16le", "-ar", strconv.Itoa(frameRate), "-ac", strconv.Itoa(channels), "pipe:1")
 }
 
 // TODO: Bit heavy here. Could probably pull out a function or two for ease of testing purposes.
-func convertToOpusFrames(videoBuf *bytes.Buffer, start string, duration string) ([][]byte, error) {
-	run := exec.Command("ffmpeg", "-i", "pipe:0", "-f", "s16le", "-ar", strconv.Itoa(frameRate), "-ac", strconv.Itoa(channels), "-ss", start, "-t", duration, "pipe:1")
+func convertToOpusFrames(input io.Reader, start string, duration string, audioFilter string) ([][]byte, error) {
+	run := exec.Command("ffmpeg", ffmpegArgs(start, duration, audioFilter)...)
 	ffmpegOut, _ := run.StdoutPipe()
 	ffmpegIn, _ := run.StdinPipe()
+	stderr := &tailBuffer{max: ffmpegStderrTail}
+	run.Stderr = stderr
 
 	go func() {
 		defer ffmpegIn.Close()
-		ffmpegIn.Write(videoBuf.Bytes())
+		io.Copy(ffmpegIn, input)
 	}()
 
 	ffmpegbuf := bufio.NewReader(ffmpegOut)
 
 	err := run.Start()
 	if err != nil {
-		return nil, errors.New("Error converting video")
+		return nil, wrapError(codeMedia, "Error converting audio", fmt.Errorf("starting ffmpeg: %v", err))
 	}
 
 	opusEncoder, _ := gopus.NewEncoder(frameRate, channels, gopus.Audio)
@@ -155,6 +424,10 @@ func convertToOpusFrames(videoBuf *bytes.Buffer, start string, duration string)
 		// If EOF or UnexpectedEOF is received, return all opusFrames because either all of the audio data was converted
 		// into opusFrames or we have some audio data (<20ms) that won't fit into a valid opus frame so throw it away for now
 		if err != nil {
+			err = run.Wait()
+			if err != nil {
+				return nil, wrapError(codeMedia, "Error converting audio", fmt.Errorf("ffmpeg: %v: %s", err, stderr.Bytes()))
+			}
 			return opusFrames, nil
 		}
 
@@ -165,67 +438,57 @@ func convertToOpusFrames(videoBuf *bytes.Buffer, start string, duration string)
 			// This branch should almost never be ran. The only time it could be is if the video data
 			// fit perfectly into 20ms opus frames with no remaining data.
 			fmt.Println("binary.Read EOF reached")
+			run.Wait()
 			return opusFrames, nil
 		}
 
 		opusFrame, err := opusEncoder.Encode(frameBuf, frameSize, maxBytes)
 		if err != nil {
-			return nil, errors.New("Error encoding audio")
+			run.Process.Kill()
+			run.Wait()
+			return nil, wrapError(codeMedia, "Error encoding audio", err)
 		}
 		opusFrames = append(opusFrames, opusFrame)
+		// A clip of exactly maxClipSeconds is fine, it's only too long once there's audio past that
+		if len(opusFrames) > maxClipFrames {
+			run.Process.Kill()
+			run.Wait()
+			return nil, newUserError(codeTooLarge, fmt.Sprintf("Clips can be at most %v seconds long", maxClipSeconds))
+		}
 	}
 }
 
-func putSoundLocal(buf *bytes.Buffer, fileName string) error {
-	file, err := os.Create("sounds/" + fileName)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	_, err = file.Write(buf.Bytes())
-	return err
+// tailBuffer keeps the last max bytes written to it, enough of ffmpeg's output to say what went wrong.
+type tailBuffer struct {
+	buf []byte
+	max int
 }
 
-func listSoundsLocal() ([]string, error) {
-	files, err := ioutil.ReadDir("./sounds")
-	if err != nil {
-		return nil, errors.New("Can't read local sound directory")
-	}
-
-	sounds := make([]string, 0)
-	for _, f := range files {
-		sounds = append(sounds, f.Name())
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
 	}
-
-	return sounds, nil
+	return len(p), nil
 }
 
-func getSoundLocal(filename string) []byte {
-	file, err := os.Open("sounds/" + filename)
-	if err != nil {
-		fmt.Println(filename, " does not exist.")
-		return nil
-	}
-	// TODO: I can probably replace all this with a Reader or something.
-	fileinfo, err := file.Stat()
-	filesize := fileinfo.Size()
-	buf := make([]byte, filesize)
-	_, err = file.Read(buf)
-	return buf
+func (t *tailBuffer) Bytes() []byte {
+	return bytes.TrimSpace(t.buf)
 }
 
+// gobEncodeOpusFrames writes the legacy clip format. New clips are written with encodeClip.
 func gobEncodeOpusFrames(opusFrames [][]byte) (*bytes.Buffer, error) {
 	network := bytes.NewBuffer(nil)
 	enc := gob.NewEncoder(network)
 	err := enc.Encode(opusAudio{ByteArray: opusFrames})
 	if err != nil {
-		return nil, errors.New("Error gobbing frames")
+		return nil, fmt.Errorf("gob encoding frames: %v", err)
 	}
 	return network, nil
 }
 
-func gobDecodeOpusFrames(data []byte) [][]byte {
+// gobDecodeOpusFrames reads the legacy clip format, a bare gob of opusAudio.
+func gobDecodeOpusFrames(data []byte) ([][]byte, error) {
 	var (
 		network    bytes.Buffer
 		opusStruct opusAudio
@@ -235,7 +498,7 @@ func gobDecodeOpusFrames(data []byte) [][]byte {
 
 	err := enc.Decode(&opusStruct)
 	if err != nil {
-		log.Fatal("gobDecodeOpusFrames error:", err)
+		return nil, fmt.Errorf("gobDecodeOpusFrames error: %v", err)
 	}
-	return opusStruct.ByteArray
+	return opusStruct.ByteArray, nil
 }
@@ -0,0 +1,201 @@
+package judgego
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultAuditLogFile = "audit.jsonl"
+	// auditQueryLimit is the most events $audit will show
+	auditQueryLimit = 20
+	// maxAuditDetail is how much of an event's detail is shown in Discord
+	maxAuditDetail = 200
+	// maxMessageLength is the most characters Discord allows in a message
+	maxMessageLength = 2000
+
+	// Types of audit events
+	eventCommand    = "command"
+	eventDelete     = "delete"
+	eventFilter     = "filter"
+	eventInduction  = "induction"
+	eventRevocation = "revocation"
+	eventClip       = "clip"
+)
+
+var (
+	// auditLogFile is the JSONL file every audit event is appended to
+	auditLogFile = auditLogPath(os.Getenv("AUDIT_LOG_FILE"))
+	// modLogChannelID is where audit events are posted for the moderators
+	modLogChannelID = os.Getenv("MOD_LOG_CHANNEL_ID")
+)
+
+// auditLock serializes writes to the audit log file
+var auditLock sync.Mutex
+
+// auditEvent is a single thing the bot did that a moderator might want to review.
+type auditEvent struct {
+	Time      time.Time `json:"time"`
+	GuildID   string    `json:"guildId"`
+	ChannelID string    `json:"channelId,omitempty"`
+	Type      string    `json:"type"`
+	UserID    string    `json:"userId,omitempty"`
+	UserName  string    `json:"userName,omitempty"`
+	Detail    string    `json:"detail"`
+}
+
+func auditLogPath(path string) string {
+	if path == "" {
+		return defaultAuditLogFile
+	}
+	return path
+}
+
+// audit records the event in the audit log file and posts it to the mod log channel, if there is
+// one. The mod log channel belongs to a single guild so other guilds' events aren't posted there.
+func audit(s *discordgo.Session, event auditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	err := appendAuditEvent(auditLogFile, event)
+	if err != nil {
+		log.Println("Failed to write audit event: ", err)
+	}
+	if s != nil && modLogChannelID != "" {
+		go func() {
+			channel, err := s.State.Channel(modLogChannelID)
+			if err != nil {
+				channel, err = s.Channel(modLogChannelID)
+			}
+			if err != nil {
+				log.Println("Failed to get mod log channel: ", err)
+				return
+			}
+			if channel.GuildID != event.GuildID {
+				return
+			}
+			_, err = s.ChannelMessageSend(modLogChannelID, formatAuditEvent(event))
+			if err != nil {
+				log.Println("Failed to post to mod log: ", err)
+			}
+		}()
+	}
+}
+
+// auditMessage audits something done on behalf of the message's author.
+func auditMessage(s *discordgo.Session, m *discordgo.Message, eventType, detail string) {
+	audit(s, auditEvent{
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		Type:      eventType,
+		UserID:    m.Author.ID,
+		UserName:  m.Author.Username,
+		Detail:    detail,
+	})
+}
+
+func appendAuditEvent(path string, event auditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// queryAuditEvents returns the guild's most recent events matching the user (if set) at or
+// after since, oldest first.
+func queryAuditEvents(path, guildID, userID string, since time.Time, limit int) ([]auditEvent, error) {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []auditEvent{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events := make([]auditEvent, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var event auditEvent
+		err = json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil {
+			// A line cut short by a crash shouldn't hide everything after it
+			continue
+		}
+		if event.GuildID != guildID || event.Time.Before(since) {
+			continue
+		}
+		if userID != "" && event.UserID != userID {
+			continue
+		}
+		events = append(events, event)
+		if len(events) > limit {
+			events = events[1:]
+		}
+	}
+	return events, scanner.Err()
+}
+
+func formatAuditEvent(event auditEvent) string {
+	detail := event.Detail
+	if runes := []rune(detail); len(runes) > maxAuditDetail {
+		detail = string(runes[:maxAuditDetail]) + "..."
+	}
+	line := fmt.Sprintf("`%v` **%v**", event.Time.Format("2006-01-02 15:04"), event.Type)
+	if event.UserName != "" {
+		line += " " + event.UserName
+	}
+	if event.ChannelID != "" {
+		line += " in <#" + event.ChannelID + ">"
+	}
+	return line + ": " + detail
+}
+
+func resolveAuditCommand(auditCmd auditCommand, guildID string) (string, error) {
+	since := auditCmd.since
+	if auditCmd.period > 0 {
+		since = time.Now().Add(-auditCmd.period)
+	}
+	events, err := queryAuditEvents(auditLogFile, guildID, auditCmd.userID, since, auditQueryLimit)
+	if err != nil {
+		return "", wrapError(codeStorage, "Error reading the audit log", err)
+	}
+	if len(events) == 0 {
+		return "Nothing in the audit log.", nil
+	}
+
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		lines = append(lines, formatAuditEvent(event))
+	}
+	// Drop the oldest events until it fits in a single message
+	resp := strings.Join(lines, "\n")
+	for len(resp) > maxMessageLength && len(lines) > 1 {
+		lines = lines[1:]
+		resp = strings.Join(lines, "\n")
+	}
+	return resp, nil
+}
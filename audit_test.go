@@ -0,0 +1,77 @@
+package judgego
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryAuditEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "judgego")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.jsonl")
+
+	events, err := queryAuditEvents(path, "g", "", time.Time{}, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+
+	start := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, userID := range []string{"1", "2", "1", "1"} {
+		event := auditEvent{Time: start.Add(time.Duration(i) * time.Hour), GuildID: "g", Type: eventCommand, UserID: userID, Detail: "$list"}
+		assert.Nil(t, appendAuditEvent(path, event))
+	}
+	assert.Nil(t, appendAuditEvent(path, auditEvent{Time: start, GuildID: "other", UserID: "1"}))
+	// A torn write is skipped
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"time":"2020-03`)
+	f.Close()
+
+	events, err = queryAuditEvents(path, "g", "", time.Time{}, 10)
+	assert.Nil(t, err)
+	assert.Len(t, events, 4)
+
+	events, err = queryAuditEvents(path, "g", "1", start.Add(time.Hour), 10)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+
+	// Only the newest events are kept, oldest first
+	events, err = queryAuditEvents(path, "g", "", time.Time{}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{start.Add(2 * time.Hour), start.Add(3 * time.Hour)}, []time.Time{events[0].Time, events[1].Time})
+}
+
+func TestFormatAuditEvent(t *testing.T) {
+	event := auditEvent{
+		Time:      time.Date(2020, 3, 1, 15, 4, 0, 0, time.UTC),
+		Type:      eventFilter,
+		UserName:  "colin",
+		ChannelID: "5",
+		Detail:    strings.Repeat("a", maxAuditDetail+1),
+	}
+	assert.Equal(t, "`2020-03-01 15:04` **filter** colin in <#5>: "+strings.Repeat("a", maxAuditDetail)+"...", formatAuditEvent(event))
+}
+
+func TestParseAuditCmd(t *testing.T) {
+	auditCmd, err := parseAuditCmd("$audit")
+	assert.Nil(t, err)
+	assert.Equal(t, auditCommand{}, auditCmd)
+
+	auditCmd, err = parseAuditCmd("$audit <@1234> 7d")
+	assert.Nil(t, err)
+	assert.Equal(t, auditCommand{userID: "1234", period: 7 * day}, auditCmd)
+
+	auditCmd, err = parseAuditCmd("$audit 2020-03-01")
+	assert.Nil(t, err)
+	assert.Equal(t, auditCommand{since: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}, auditCmd)
+
+	for _, msg := range []string{"$audit colin", "$audit <@1234> week now"} {
+		_, err = parseAuditCmd(msg)
+		assert.NotNil(t, err, msg)
+	}
+}
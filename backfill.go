@@ -0,0 +1,106 @@
+package judgego
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// backfillPageSize is the number of messages pulled per request while backfilling
+	backfillPageSize = 100
+	// backfillDateFormat is the format of the optional since date passed to $halls backfill
+	backfillDateFormat = "2006-01-02"
+)
+
+// backfills tracks the channels currently being backfilled so a channel is only scanned once at a time.
+var backfills = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+// startBackfill kicks off a backfill of the channel in the background. Progress is reported in
+// replyChannelID by editing a single message. Only channels in the guild can be backfilled.
+func startBackfill(s *discordgo.Session, guildID, replyChannelID string, hallsCmd hallsCommand) error {
+	channel, err := s.State.Channel(hallsCmd.channelID)
+	if err != nil {
+		channel, err = s.Channel(hallsCmd.channelID)
+	}
+	if err != nil || channel.GuildID != guildID {
+		return newUserError(codeNotFound, "That channel isn't in this server")
+	}
+
+	for _, rule := range getHallRules(guildID) {
+		if rule.ChannelID == hallsCmd.channelID {
+			return newUserError(codeConflict, "That's the channel for the "+rule.Name+" hall")
+		}
+	}
+
+	backfills.Lock()
+	defer backfills.Unlock()
+	if backfills.m[hallsCmd.channelID] {
+		return newUserError(codeConflict, "That channel is already being backfilled")
+	}
+	backfills.m[hallsCmd.channelID] = true
+
+	go func() {
+		backfillChannel(s, guildID, replyChannelID, hallsCmd.channelID, hallsCmd.since)
+		backfills.Lock()
+		delete(backfills.m, hallsCmd.channelID)
+		backfills.Unlock()
+	}()
+	return nil
+}
+
+// backfillChannel pages through the channel's history from newest to oldest, stopping at since,
+// and inducts every message that qualifies for a hall but was missed while the bot was offline.
+func backfillChannel(s *discordgo.Session, guildID, replyChannelID, channelID string, since time.Time) {
+	progress, err := s.ChannelMessageSend(replyChannelID, fmt.Sprintf("Backfilling <#%v>...", channelID))
+	if err != nil {
+		log.Println("Failed to send backfill progress: ", err)
+		return
+	}
+	report := func(status string) {
+		_, err := s.ChannelMessageEdit(progress.ChannelID, progress.ID, status)
+		if err != nil {
+			log.Println("Failed to update backfill progress: ", err)
+		}
+	}
+
+	scanned, inducted := 0, 0
+	before := ""
+	for {
+		messages, err := s.ChannelMessages(channelID, backfillPageSize, before, "", "")
+		if err != nil {
+			log.Println("Failed to get channel messages: ", err)
+			report(fmt.Sprintf("Backfill of <#%v> failed after %v messages, inducted %v", channelID, scanned, inducted))
+			return
+		}
+
+		done := len(messages) < backfillPageSize
+		for _, message := range messages {
+			ts := message.Timestamp
+			if ts.Before(since) {
+				done = true
+				break
+			}
+			scanned++
+			if alreadyInducted(message.ChannelID, message.ID) {
+				continue
+			}
+			// The reactions have been there since who knows when, date the induction by the post
+			if tryInduct(s, message, guildID, ts.UTC()) {
+				inducted++
+			}
+		}
+		if done {
+			break
+		}
+		before = messages[len(messages)-1].ID
+		report(fmt.Sprintf("Backfilling <#%v>: scanned %v messages, inducted %v", channelID, scanned, inducted))
+	}
+	report(fmt.Sprintf("Backfill of <#%v> done: scanned %v messages, inducted %v", channelID, scanned, inducted))
+}
@@ -0,0 +1,134 @@
+package judgego
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// The on-disk clip format is a small header followed by length prefixed opus frames and a
+// trailing checksum. Everything is big endian.
+//
+//	magic      [4]byte  "JGOC"
+//	version    uint16
+//	frameRate  uint32
+//	channels   uint16
+//	frameSize  uint16
+//	frameCount uint32
+//	frames     frameCount * (uint16 length, length bytes)
+//	checksum   uint32   CRC-32 (IEEE) of everything above
+const (
+	clipMagic   = "JGOC"
+	clipVersion = 1
+	// clipHeaderSize is the number of bytes before the first frame
+	clipHeaderSize = 4 + 2 + 4 + 2 + 2 + 4
+	// clipChecksumSize is the number of bytes in the trailing checksum
+	clipChecksumSize = 4
+)
+
+var (
+	errClipTruncated = errors.New("clip data is truncated")
+	errClipChecksum  = errors.New("clip checksum mismatch")
+)
+
+// clipHeader describes the audio stored in a clip.
+type clipHeader struct {
+	Version    uint16
+	FrameRate  uint32
+	Channels   uint16
+	FrameSize  uint16
+	FrameCount uint32
+}
+
+// encodeClip serializes the opus frames into the current clip format.
+func encodeClip(opusFrames [][]byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteString(clipMagic)
+	header := clipHeader{
+		Version:    clipVersion,
+		FrameRate:  uint32(frameRate),
+		Channels:   uint16(channels),
+		FrameSize:  uint16(frameSize),
+		FrameCount: uint32(len(opusFrames)),
+	}
+	err := binary.Write(buf, binary.BigEndian, header)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, frame := range opusFrames {
+		if len(frame) > 0xFFFF {
+			return nil, fmt.Errorf("frame %v is too large to encode (%v bytes)", i, len(frame))
+		}
+		binary.Write(buf, binary.BigEndian, uint16(len(frame)))
+		buf.Write(frame)
+	}
+
+	binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// decodeClip deserializes a stored clip into its opus frames. Clips that predate the
+// header are bare gobs of opusAudio and are still accepted.
+func decodeClip(data []byte) ([][]byte, error) {
+	if !bytes.HasPrefix(data, []byte(clipMagic)) {
+		return gobDecodeOpusFrames(data)
+	}
+
+	header, frames, err := readClip(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.FrameRate != uint32(frameRate) || header.Channels != uint16(channels) || header.FrameSize != uint16(frameSize) {
+		return nil, fmt.Errorf("unsupported clip audio: %vHz, %v channels, %v samples per frame", header.FrameRate, header.Channels, header.FrameSize)
+	}
+	return frames, nil
+}
+
+func readClip(data []byte) (clipHeader, [][]byte, error) {
+	var header clipHeader
+	if len(data) < clipHeaderSize+clipChecksumSize {
+		return header, nil, errClipTruncated
+	}
+
+	body := data[:len(data)-clipChecksumSize]
+	checksum := binary.BigEndian.Uint32(data[len(data)-clipChecksumSize:])
+	if crc32.ChecksumIEEE(body) != checksum {
+		return header, nil, errClipChecksum
+	}
+
+	r := bytes.NewReader(body[len(clipMagic):])
+	err := binary.Read(r, binary.BigEndian, &header)
+	if err != nil {
+		return header, nil, errClipTruncated
+	}
+	if header.Version != clipVersion {
+		return header, nil, fmt.Errorf("unsupported clip version %v", header.Version)
+	}
+
+	// Every frame needs at least its length prefix so this also guards against absurd counts
+	if int64(header.FrameCount)*2 > int64(r.Len()) {
+		return header, nil, errClipTruncated
+	}
+	frames := make([][]byte, 0, header.FrameCount)
+	for i := uint32(0); i < header.FrameCount; i++ {
+		var frameLen uint16
+		err = binary.Read(r, binary.BigEndian, &frameLen)
+		if err != nil {
+			return header, nil, errClipTruncated
+		}
+		frame := make([]byte, frameLen)
+		_, err = io.ReadFull(r, frame)
+		if err != nil {
+			return header, nil, errClipTruncated
+		}
+		frames = append(frames, frame)
+	}
+	if r.Len() != 0 {
+		return header, nil, fmt.Errorf("clip has %v unexpected trailing bytes", r.Len())
+	}
+	return header, frames, nil
+}
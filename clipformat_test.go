@@ -0,0 +1,44 @@
+package judgego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFrames = [][]byte{{1, 2, 3}, {}, {4, 5, 6, 7}}
+
+func TestClipRoundTrip(t *testing.T) {
+	data, err := encodeClip(testFrames)
+	assert.Nil(t, err)
+	assert.Equal(t, clipMagic, string(data[:4]))
+
+	frames, err := decodeClip(data)
+	assert.Nil(t, err)
+	assert.Equal(t, testFrames, frames)
+}
+
+func TestDecodeLegacyClip(t *testing.T) {
+	legacy, err := gobEncodeOpusFrames(testFrames)
+	assert.Nil(t, err)
+
+	frames, err := decodeClip(legacy.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, len(testFrames), len(frames))
+	assert.Equal(t, testFrames[0], frames[0])
+}
+
+func TestDecodeCorruptClip(t *testing.T) {
+	data, _ := encodeClip(testFrames)
+
+	flipped := append([]byte{}, data...)
+	flipped[clipHeaderSize+1] ^= 0xFF
+	_, err := decodeClip(flipped)
+	assert.Equal(t, errClipChecksum, err)
+
+	_, err = decodeClip(data[:clipHeaderSize])
+	assert.Equal(t, errClipTruncated, err)
+
+	_, err = decodeClip([]byte("not a clip at all"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,588 @@
+package judgego
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// commandPrefix starts every command
+const commandPrefix = "$"
+
+var errNoPermission = newUserError(codePermission, "You don't have permission to do that")
+
+// Command is a bot command. The zero value of a command describes it and is what gets
+// registered, Parse returns a copy filled in from the message.
+type Command interface {
+	Name() string
+	Aliases() []string
+	// Usage is the command's arguments, starting with its name
+	Usage() string
+	Description() string
+	Parse(msg string) (Command, error)
+	Execute(ctx *commandContext) (commandResult, error)
+}
+
+// commandContext is where a command was invoked from and by whom.
+type commandContext struct {
+	session   *discordgo.Session
+	guildID   string
+	channelID string
+	author    *discordgo.User
+	// message is the message that invoked the command, needed for attachments and permission checks
+	message *discordgo.Message
+}
+
+func newMessageContext(s *discordgo.Session, m *discordgo.Message) *commandContext {
+	return &commandContext{session: s, guildID: m.GuildID, channelID: m.ChannelID, author: m.Author, message: m}
+}
+
+func (ctx *commandContext) isAdmin() bool {
+	return isAdmin(ctx.session, ctx.message)
+}
+
+// commandResult contains the result of whatever resolving a command. It allows
+// us to control the bot sending text or audio and/or deleting user messages.
+type commandResult struct {
+	resp string
+	// sounds are queued in order, only their name and audio need to be set
+	sounds []*queuedSound
+	// announce replies with which sounds were queued, once they have been
+	announce    bool
+	file        *discordgo.File
+	keepUserMsg bool
+}
+
+// commands are all the registered commands in the order $help lists them. commandIndex maps
+// every name and alias to its command.
+var (
+	commands     []Command
+	commandIndex = map[string]Command{}
+)
+
+func init() {
+	registerCommands(
+		playCommand{}, randomCommand{}, shuffleCommand{}, listCommand{}, searchCommand{}, tagCommand{}, infoCommand{}, ripCommand{}, uploadCommand{}, exportCommand{},
+		importCommand{}, deleteCommand{}, renameCommand{}, queueCommand{}, skipCommand{}, stopCommand{},
+		clearCommand{}, hallCommand{}, hallsCommand{}, filterCommand{}, auditCommand{}, helpCommand{},
+	)
+}
+
+func registerCommands(cmds ...Command) {
+	for _, cmd := range cmds {
+		commands = append(commands, cmd)
+		commandIndex[cmd.Name()] = cmd
+		for _, alias := range cmd.Aliases() {
+			commandIndex[alias] = cmd
+		}
+	}
+}
+
+// findCommand looks a command up by the first token of a message, like $play.
+func findCommand(token string) (Command, bool) {
+	if !strings.HasPrefix(token, commandPrefix) {
+		return nil, false
+	}
+	cmd, ok := commandIndex[strings.ToLower(strings.TrimPrefix(token, commandPrefix))]
+	return cmd, ok
+}
+
+// usageError is the error for a command that was used wrong.
+func usageError(cmd Command) error {
+	return invalidInput("Usage: " + formatUsage(cmd))
+}
+
+// formatUsage prefixes each of the forms in the command's usage, which are separated by |.
+func formatUsage(cmd Command) string {
+	forms := strings.Split(cmd.Usage(), " | ")
+	for i, form := range forms {
+		forms[i] = "`" + commandPrefix + form + "`"
+	}
+	return strings.Join(forms, " or ")
+}
+
+// resolveCommand runs the command and audits it. Errors are logged and their user message
+// becomes the response.
+func resolveCommand(ctx *commandContext, cmd Command) commandResult {
+	cmdResult, err := cmd.Execute(ctx)
+	if err != nil {
+		logError(fmt.Sprintf("%v%v for %v in %v", commandPrefix, cmd.Name(), ctx.author.Username, ctx.guildID), err)
+		cmdResult.resp = userMessage(err)
+	}
+	if _, ok := cmd.(messageCommand); !ok {
+		detail := ctx.message.Content
+		if err != nil {
+			detail += " (" + userMessage(err) + ")"
+		}
+		auditMessage(ctx.session, ctx.message, eventCommand, detail)
+	}
+	return cmdResult
+}
+
+func (ripCommand) Name() string      { return "rip" }
+func (ripCommand) Aliases() []string { return nil }
+func (ripCommand) Usage() string {
+	return "rip <sound_name> <url> <start_time> <end_time> [--normalize] [--fadein 200ms] [--fadeout 300ms] [--gain -3dB] [--speed 1.5] [--reverse]"
+}
+func (ripCommand) Description() string {
+	return "Creates a sound from a YouTube video or a link to an audio/video file. Times look like 1m30s"
+}
+func (ripCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseRipCmd(msg)
+	return cmd, err
+}
+func (ripCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		stringOption("name", "What to call the sound", true),
+		stringOption("url", "A YouTube video or a link to an audio/video file", true),
+		stringOption("start", "Where the sound starts, like 1m30s", true),
+		stringOption("end", "Where the sound ends, like 1m35s", true),
+		stringOption("effects", "Like --normalize --fadein 200ms --fadeout 300ms --gain -3dB --speed 1.5 --reverse", false),
+	}
+}
+func (c ripCommand) Execute(ctx *commandContext) (commandResult, error) {
+	err := ripSound(c, ctx.author)
+	auditClip(ctx.session, ctx.message, c.name, err)
+	return commandResult{resp: "Sound successfully created!"}, err
+}
+
+func (playCommand) Name() string      { return "play" }
+func (playCommand) Aliases() []string { return []string{"p"} }
+func (playCommand) Usage() string     { return "play <sound_name>" }
+func (playCommand) Description() string {
+	return "Queues up a sound in your voice channel. Close enough names work if only one sound fits"
+}
+func (playCommand) Parse(msg string) (Command, error) {
+	cmd, err := parsePlayCmd(msg)
+	return cmd, err
+}
+func (playCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{soundOption("name", "The sound to play")}
+}
+func (c playCommand) Execute(ctx *commandContext) (commandResult, error) {
+	name, audio, err := playSound(c)
+	if err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{sounds: []*queuedSound{{name: name, audio: audio}}}, nil
+}
+
+func (randomCommand) Name() string      { return "random" }
+func (randomCommand) Aliases() []string { return []string{"r"} }
+func (randomCommand) Usage() string     { return "random [tag] [--weight plays|recent]" }
+func (randomCommand) Description() string {
+	return "Queues up a random sound, optionally one with the tag. Weighting favors often played or new sounds"
+}
+func (randomCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseRandomCmd(msg)
+	return cmd, err
+}
+func (randomCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		stringOption("tag", "Only pick sounds with this tag", false),
+		weightOption(),
+	}
+}
+func (c randomCommand) Execute(ctx *commandContext) (commandResult, error) {
+	return shuffleResult(shuffleSounds(1, c.tag, c.weight))
+}
+
+func (shuffleCommand) Name() string      { return "shuffle" }
+func (shuffleCommand) Aliases() []string { return nil }
+func (shuffleCommand) Usage() string {
+	return "shuffle <count> [tag] [--weight plays|recent]"
+}
+func (shuffleCommand) Description() string {
+	return fmt.Sprintf("Queues up to %v different random sounds, optionally ones with the tag. Weighting works like $random", maxShuffle)
+}
+func (shuffleCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseShuffleCmd(msg)
+	return cmd, err
+}
+func (shuffleCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		integerOption("count", "How many sounds to queue", 1, maxShuffle),
+		stringOption("tag", "Only pick sounds with this tag", false),
+		weightOption(),
+	}
+}
+func (c shuffleCommand) Execute(ctx *commandContext) (commandResult, error) {
+	return shuffleResult(shuffleSounds(c.count, c.tag, c.weight))
+}
+
+// shuffleResult queues the randomly picked sounds, saying which they were once they're queued.
+func shuffleResult(sounds []*queuedSound, err error) (commandResult, error) {
+	if err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{sounds: sounds, announce: true}, nil
+}
+
+func (listCommand) Name() string      { return "list" }
+func (listCommand) Aliases() []string { return []string{"ls"} }
+func (listCommand) Usage() string     { return "list [tag]" }
+func (listCommand) Description() string {
+	return "Lists all available sounds, or the ones with the tag"
+}
+func (listCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseListCmd(msg)
+	return cmd, err
+}
+func (listCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{stringOption("tag", "Only list sounds with this tag", false)}
+}
+func (c listCommand) Execute(ctx *commandContext) (commandResult, error) {
+	resp, err := listSounds(c)
+	return commandResult{resp: resp}, err
+}
+
+func (searchCommand) Name() string        { return "search" }
+func (searchCommand) Aliases() []string   { return nil }
+func (searchCommand) Usage() string       { return "search <query>" }
+func (searchCommand) Description() string { return "Finds sounds by name or tag" }
+func (searchCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseSearchCmd(msg)
+	return cmd, err
+}
+func (searchCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{stringOption("query", "Part of a sound's name or tag", true)}
+}
+func (c searchCommand) Execute(ctx *commandContext) (commandResult, error) {
+	resp, err := searchSounds(c)
+	return commandResult{resp: resp}, err
+}
+
+func (tagCommand) Name() string      { return "tag" }
+func (tagCommand) Aliases() []string { return nil }
+func (tagCommand) Usage() string     { return "tag <sound_name> <tag...>" }
+func (tagCommand) Description() string {
+	return "Tags a sound so it shows up in $list <tag> and $search. Tags starting with - are removed"
+}
+func (tagCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseTagCmd(msg)
+	return cmd, err
+}
+func (tagCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		soundOption("name", "The sound to tag"),
+		stringOption("tags", "Tags to add, separated by spaces. Start one with - to remove it", true),
+	}
+}
+func (c tagCommand) Execute(ctx *commandContext) (commandResult, error) {
+	resp, err := tagSound(c)
+	return commandResult{resp: resp}, err
+}
+
+func (infoCommand) Name() string      { return "info" }
+func (infoCommand) Aliases() []string { return nil }
+func (infoCommand) Usage() string     { return "info <sound_name>" }
+func (infoCommand) Description() string {
+	return "Shows where a sound was ripped from, who ripped it and how often it's been played"
+}
+func (infoCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseInfoCmd(msg)
+	return cmd, err
+}
+func (infoCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{soundOption("name", "The sound to show info for")}
+}
+func (c infoCommand) Execute(ctx *commandContext) (commandResult, error) {
+	resp, err := soundInfo(c)
+	return commandResult{resp: resp}, err
+}
+
+func (uploadCommand) Name() string      { return "upload" }
+func (uploadCommand) Aliases() []string { return nil }
+func (uploadCommand) Usage() string     { return "upload <sound_name> [start_time] [end_time]" }
+func (uploadCommand) Description() string {
+	return "Creates a sound from an attached " + strings.Join(uploadExtensions, ", ") + " file"
+}
+func (uploadCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseUploadCmd(msg)
+	return cmd, err
+}
+func (uploadCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		stringOption("name", "What to call the sound", true),
+		attachmentOption("file", "An "+strings.Join(uploadExtensions, ", ")+" file"),
+		stringOption("start", "Where the sound starts, like 1m30s", false),
+		stringOption("end", "Where the sound ends, like 1m35s", false),
+	}
+}
+func (c uploadCommand) Execute(ctx *commandContext) (commandResult, error) {
+	err := uploadSound(c, ctx.message)
+	auditClip(ctx.session, ctx.message, c.name, err)
+	return commandResult{resp: "Sound successfully created!"}, err
+}
+
+func (exportCommand) Name() string        { return "export" }
+func (exportCommand) Aliases() []string   { return nil }
+func (exportCommand) Usage() string       { return "export <sound_name>" }
+func (exportCommand) Description() string { return "Uploads a sound as an Ogg/Opus file" }
+func (exportCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseExportCmd(msg)
+	return cmd, err
+}
+func (exportCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{soundOption("name", "The sound to export")}
+}
+func (c exportCommand) Execute(ctx *commandContext) (commandResult, error) {
+	file, err := exportSound(c)
+	return commandResult{file: file}, err
+}
+
+func (importCommand) Name() string        { return "import" }
+func (importCommand) Aliases() []string   { return nil }
+func (importCommand) Usage() string       { return "import <sound_name>" }
+func (importCommand) Description() string { return "Creates a sound from an attached .ogg file" }
+func (importCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseImportCmd(msg)
+	return cmd, err
+}
+func (importCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		stringOption("name", "What to call the sound", true),
+		attachmentOption("file", "An Ogg/Opus file"),
+	}
+}
+func (c importCommand) Execute(ctx *commandContext) (commandResult, error) {
+	err := importSound(c, ctx.message)
+	auditClip(ctx.session, ctx.message, c.name, err)
+	return commandResult{resp: "Sound successfully imported!"}, err
+}
+
+func (deleteCommand) Name() string        { return "delete" }
+func (deleteCommand) Aliases() []string   { return []string{"rm"} }
+func (deleteCommand) Usage() string       { return "delete <sound_name>" }
+func (deleteCommand) Description() string { return "Deletes a sound. Admin only" }
+func (deleteCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseDeleteCmd(msg)
+	return cmd, err
+}
+func (deleteCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{soundOption("name", "The sound to delete")}
+}
+func (c deleteCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if !ctx.isAdmin() {
+		return commandResult{}, errNoPermission
+	}
+	return commandResult{resp: "Sound successfully deleted!"}, deleteSound(c)
+}
+
+func (renameCommand) Name() string        { return "rename" }
+func (renameCommand) Aliases() []string   { return []string{"mv"} }
+func (renameCommand) Usage() string       { return "rename <old_name> <new_name>" }
+func (renameCommand) Description() string { return "Renames a sound. Admin only" }
+func (renameCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseRenameCmd(msg)
+	return cmd, err
+}
+func (renameCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		soundOption("old_name", "The sound to rename"),
+		stringOption("new_name", "Its new name", true),
+	}
+}
+func (c renameCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if !ctx.isAdmin() {
+		return commandResult{}, errNoPermission
+	}
+	return commandResult{resp: "Sound successfully renamed!"}, renameSound(c)
+}
+
+func (queueCommand) Name() string                      { return "queue" }
+func (queueCommand) Aliases() []string                 { return []string{"q"} }
+func (queueCommand) Usage() string                     { return "queue" }
+func (queueCommand) Description() string               { return "Shows what's playing and what's queued up" }
+func (queueCommand) Parse(msg string) (Command, error) { return queueCommand{}, nil }
+func (queueCommand) Execute(ctx *commandContext) (commandResult, error) {
+	return commandResult{resp: showQueue(ctx.session, ctx.guildID)}, nil
+}
+
+func (skipCommand) Name() string                      { return "skip" }
+func (skipCommand) Aliases() []string                 { return nil }
+func (skipCommand) Usage() string                     { return "skip" }
+func (skipCommand) Description() string               { return "Skips the sound that's currently playing" }
+func (skipCommand) Parse(msg string) (Command, error) { return skipCommand{}, nil }
+func (skipCommand) Execute(ctx *commandContext) (commandResult, error) {
+	return commandResult{resp: skipSound(ctx.session, ctx.guildID)}, nil
+}
+
+func (stopCommand) Name() string      { return "stop" }
+func (stopCommand) Aliases() []string { return nil }
+func (stopCommand) Usage() string     { return "stop" }
+func (stopCommand) Description() string {
+	return "Stops playback, clears the queue and leaves the voice channel"
+}
+func (stopCommand) Parse(msg string) (Command, error) { return stopCommand{}, nil }
+func (stopCommand) Execute(ctx *commandContext) (commandResult, error) {
+	return commandResult{resp: stopPlayback(ctx.session, ctx.guildID)}, nil
+}
+
+func (clearCommand) Name() string                      { return "clear" }
+func (clearCommand) Aliases() []string                 { return nil }
+func (clearCommand) Usage() string                     { return "clear" }
+func (clearCommand) Description() string               { return "Clears everything waiting in the queue" }
+func (clearCommand) Parse(msg string) (Command, error) { return clearCommand{}, nil }
+func (clearCommand) Execute(ctx *commandContext) (commandResult, error) {
+	return commandResult{resp: clearQueue(ctx.session, ctx.guildID)}, nil
+}
+
+func (hallCommand) Name() string      { return "hall" }
+func (hallCommand) Aliases() []string { return nil }
+func (hallCommand) Usage() string {
+	return "hall list | hall add <name> <emoji> <threshold> <#channel> [--count-author] [--reversal keep|edit|remove] [template] | hall remove <name>"
+}
+func (hallCommand) Description() string {
+	return "Lists or sets up the halls messages get voted into. The template can use {date}, {author}, {voters} and {hall}. Adding and removing is admin only"
+}
+func (hallCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseHallCmd(msg)
+	return cmd, err
+}
+func (c hallCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{argsOption(c)}
+}
+func (c hallCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if c.action != "list" && !ctx.isAdmin() {
+		return commandResult{}, errNoPermission
+	}
+	resp, err := resolveHallCommand(c, ctx.guildID)
+	return commandResult{resp: resp}, err
+}
+
+func (hallsCommand) Name() string      { return "halls" }
+func (hallsCommand) Aliases() []string { return nil }
+func (hallsCommand) Usage() string {
+	return "halls [top [hall]|me|@user] [week|month|year|all|14d] | halls backfill <#channel> [since " + backfillDateFormat + "]"
+}
+func (hallsCommand) Description() string {
+	return "Shows hall leaderboards and stats. Backfill inducts anything missed while the bot was offline and is admin only"
+}
+func (hallsCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseHallsCmd(msg)
+	return cmd, err
+}
+func (c hallsCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{argsOption(c)}
+}
+func (c hallsCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if c.action != "backfill" {
+		return commandResult{resp: resolveHallsCommand(c, ctx.guildID, ctx.author.ID)}, nil
+	}
+	if !ctx.isAdmin() {
+		return commandResult{}, errNoPermission
+	}
+	return commandResult{}, startBackfill(ctx.session, ctx.guildID, ctx.channelID, c)
+}
+
+func (filterCommand) Name() string      { return "filter" }
+func (filterCommand) Aliases() []string { return nil }
+func (filterCommand) Usage() string {
+	return "filter list | filter add <name> <word|regex> <pattern> [" + strings.Join(filterActions, "] [") + "] | filter remove <name>"
+}
+func (filterCommand) Description() string {
+	return "Manages the words and patterns that aren't allowed in messages. Admin only"
+}
+func (filterCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseFilterCmd(msg)
+	return cmd, err
+}
+func (c filterCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{argsOption(c)}
+}
+func (c filterCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if !ctx.isAdmin() {
+		return commandResult{}, errNoPermission
+	}
+	resp, err := resolveFilterCommand(c, ctx.guildID)
+	return commandResult{resp: resp}, err
+}
+
+func (auditCommand) Name() string      { return "audit" }
+func (auditCommand) Aliases() []string { return nil }
+func (auditCommand) Usage() string     { return "audit [@user] [7d|week|" + backfillDateFormat + "]" }
+func (auditCommand) Description() string {
+	return "Shows the latest audit events, optionally for one user and since a period or date. Admin only"
+}
+func (auditCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseAuditCmd(msg)
+	return cmd, err
+}
+func (c auditCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{argsOption(c)}
+}
+func (c auditCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if !ctx.isAdmin() {
+		return commandResult{}, errNoPermission
+	}
+	resp, err := resolveAuditCommand(c, ctx.guildID)
+	return commandResult{resp: resp}, err
+}
+
+// helpCommand contains all pertinent info to resolve the $help command
+type helpCommand struct {
+	topic string
+}
+
+func (helpCommand) Name() string        { return "help" }
+func (helpCommand) Aliases() []string   { return []string{"commands"} }
+func (helpCommand) Usage() string       { return "help [command]" }
+func (helpCommand) Description() string { return "Lists every command or explains one" }
+func (helpCommand) Parse(msg string) (Command, error) {
+	cmd := helpCommand{}
+	tokens := strings.Fields(msg)
+	if len(tokens) > 2 {
+		return cmd, usageError(cmd)
+	}
+	if len(tokens) == 2 {
+		cmd.topic = strings.TrimPrefix(tokens[1], commandPrefix)
+	}
+	return cmd, nil
+}
+func (helpCommand) SlashOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{stringOption("command", "The command to explain", false)}
+}
+func (c helpCommand) Execute(ctx *commandContext) (commandResult, error) {
+	if c.topic == "" {
+		return commandResult{resp: commandList()}, nil
+	}
+	cmd, ok := findCommand(commandPrefix + c.topic)
+	if !ok {
+		return commandResult{}, newUserError(codeNotFound, "No command named "+c.topic+". Try "+commandPrefix+"help")
+	}
+	return commandResult{resp: commandHelp(cmd)}, nil
+}
+
+func commandList() string {
+	lines := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		lines = append(lines, "`"+commandPrefix+cmd.Name()+"` - "+cmd.Description())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func commandHelp(cmd Command) string {
+	lines := []string{formatUsage(cmd), cmd.Description()}
+	if aliases := cmd.Aliases(); len(aliases) > 0 {
+		sorted := append([]string{}, aliases...)
+		sort.Strings(sorted)
+		lines = append(lines, "Aliases: "+commandPrefix+strings.Join(sorted, ", "+commandPrefix))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (messageCommand) Name() string        { return "" }
+func (messageCommand) Aliases() []string   { return nil }
+func (messageCommand) Usage() string       { return "" }
+func (messageCommand) Description() string { return "" }
+func (messageCommand) Parse(msg string) (Command, error) {
+	cmd, err := parseMessageCmd(msg)
+	return cmd, err
+}
+func (messageCommand) Execute(ctx *commandContext) (commandResult, error) {
+	warning, deleteMsg := moderateMessage(ctx.session, ctx.message)
+	return commandResult{resp: warning, keepUserMsg: !deleteMsg}, nil
+}
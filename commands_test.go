@@ -0,0 +1,70 @@
+package judgego
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisteredCommands(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, cmd := range commands {
+		assert.NotEmpty(t, cmd.Description(), cmd.Name())
+		assert.True(t, strings.HasPrefix(cmd.Usage(), cmd.Name()), cmd.Name())
+		for _, name := range append([]string{cmd.Name()}, cmd.Aliases()...) {
+			assert.False(t, seen[name], "%v is registered twice", name)
+			seen[name] = true
+		}
+	}
+}
+
+func TestParseMsg(t *testing.T) {
+	cmd, err := parseMsg("$play dethklok")
+	assert.Nil(t, err)
+	assert.Equal(t, playCommand{"dethklok"}, cmd)
+
+	cmd, err = parseMsg("$p dethklok")
+	assert.Nil(t, err)
+	assert.Equal(t, playCommand{"dethklok"}, cmd)
+
+	cmd, err = parseMsg("$halls")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "top"}, cmd)
+
+	cmd, err = parseMsg("$queue")
+	assert.Nil(t, err)
+	assert.Equal(t, queueCommand{}, cmd)
+
+	cmd, err = parseMsg("$notacommand hi")
+	assert.Nil(t, err)
+	assert.Equal(t, messageCommand{"$notacommand hi"}, cmd)
+
+	_, err = parseMsg("$play")
+	assert.EqualError(t, err, "Usage: `$play <sound_name>`")
+
+	_, err = parseMsg("$hall remove")
+	assert.True(t, strings.HasPrefix(err.Error(), "Usage: `$hall list` or `$hall add "), err.Error())
+}
+
+func TestHelpCommand(t *testing.T) {
+	cmd, err := parseMsg("$help")
+	assert.Nil(t, err)
+	result, err := cmd.Execute(&commandContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, len(commands), strings.Count(result.resp, "\n")+1)
+	assert.Contains(t, result.resp, "`$play` - Queues up a sound in your voice channel")
+
+	cmd, err = parseMsg("$help $p")
+	assert.Nil(t, err)
+	result, err = cmd.Execute(&commandContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "`$play <sound_name>`\nQueues up a sound in your voice channel. Close enough names work if only one sound fits\nAliases: $p", result.resp)
+
+	cmd, _ = parseMsg("$help dance")
+	_, err = cmd.Execute(&commandContext{})
+	assert.NotNil(t, err)
+
+	_, err = parseMsg("$help play list")
+	assert.NotNil(t, err)
+}
@@ -3,44 +3,72 @@ package judgego
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
 	"strings"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 // TODO: Create actual structs so we can use reciever functions. No reason to be passing session around as much I am.
 
-// TODO: General error handling can be updated. I don't want to obfuscate the actual error for a user
-// friendly message which is the current pattern. Can probably just extend Error and then print the
-// actual error to log and respond to the user with the nice message.
-
 const (
 	// deleteDelay is the duration of time to wait before deleting a message
 	deleteDelay = 8 * time.Second
-	// censorRegex is a regex of all banned words
-	censorRegex = `\b(wakeley|wakefest)\b`
-	// inductionMinCount is the minimum amount of reactions a post must get to be inducted
-	inductionMinCount = 3
-	// reactorCount is the number of users to pull who reacted on a message
-	reactorCount = 10
+	// reactorPageSize is the number of users to pull per request for who reacted on a message
+	reactorPageSize = 100
+	// maxAttachmentSize is the largest attachment we'll download
+	maxAttachmentSize = 8 << 20
 )
 
 var (
-	hallOfFameChanID  = os.Getenv("HALL_OF_FAME_ID")
-	hallOfShameChanID = os.Getenv("HALL_OF_SHAME_ID")
-	guildID           = os.Getenv("GUILD_ID")
+	guildID     = os.Getenv("GUILD_ID")
+	adminRoleID = os.Getenv("ADMIN_ROLE_ID")
+	// hallMinMemberAge is how long someone has to be in the guild before their hall votes count
+	hallMinMemberAge, _ = time.ParseDuration(os.Getenv("HALL_MIN_MEMBER_AGE"))
 )
 
 // Start is the main initialization function for the bot.
 func Start() {
-	if os.Getenv("S3_PERSISTENCE") == "false" {
-		initSoundDir()
+	var err error
+	soundStore, err = newSoundStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	metadataStore, err = newMetadataStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderationStore, err = newModerationStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = loadFilterRules()
+	if err != nil {
+		log.Fatal(err)
+	}
+	hallStore, err = newHallStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	legacyHallStore, err := newLegacyHallStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = loadHallRules(legacyHallStore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = loadReactionHistory(legacyHallStore)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	token := os.Getenv("DISCORD_BOT_TOKEN")
@@ -51,10 +79,22 @@ func Start() {
 
 	dg.AddHandler(messageCreate)
 	dg.AddHandler(messageReactionAdd)
+	dg.AddHandler(messageReactionRemove)
+	dg.AddHandler(messageReactionRemoveAll)
+	dg.AddHandler(messageDelete)
+	dg.AddHandler(registerSlashCommands)
+	dg.AddHandler(interactionCreate)
+	// Reading commands needs the message content intent, finding members by name needs the members intent
+	dg.Identify.Intents = discordgo.IntentsAllWithoutPrivileged | discordgo.IntentsMessageContent | discordgo.IntentsGuildMembers
 
 	err = dg.Open()
 	if err != nil {
-		log.Fatal(err)
+		// Discord closes the connection with 4014 when the bot asks for intents it wasn't granted
+		if strings.Contains(err.Error(), "4014") {
+			log.Fatalf("Discord refused the connection because of disallowed intents (close code 4014). "+
+				"Turn on the Message Content and Server Members privileged intents for the bot in the Discord developer portal: %v", err)
+		}
+		log.Fatalf("Failed to connect to Discord: %v", err)
 	}
 
 	fmt.Println("Bot is now running.  Press CTRL-C to exit.")
@@ -73,79 +113,213 @@ func messageReactionAdd(s *discordgo.Session, event *discordgo.MessageReactionAd
 	message, err := s.ChannelMessage(event.ChannelID, event.MessageID)
 	if err != nil {
 		log.Printf("Message does not exist: %v", err)
+		return
+	}
+
+	tryInduct(s, message, event.GuildID, time.Now().UTC())
+}
+
+// tryInduct checks the message against each of the guild's halls and inducts it into the first
+// one it qualifies for. Returns whether the message was inducted.
+func tryInduct(s *discordgo.Session, message *discordgo.Message, guildID string, inductedAt time.Time) bool {
+	for _, rule := range getHallRules(guildID) {
+		reaction := findReaction(message, rule.Emoji)
+		if reaction == nil || reaction.Count < rule.Threshold {
+			continue
+		}
+
+		voters, err := getVoters(s, message, guildID, rule)
+		if err != nil {
+			log.Println("Failed to get reactors: ", err)
+			continue
+		}
+		if len(voters) < rule.Threshold {
+			continue
+		}
+
+		post, err := addToHall(s, message, guildID, rule, voters)
+		if err != nil {
+			continue
+		}
+		reactors := make([]string, 0, len(voters))
+		for _, voter := range voters {
+			reactors = append(reactors, voter.ID)
+		}
+		err = inductMessage(induction{
+			Hall:          rule.Name,
+			GuildID:       guildID,
+			ChannelID:     message.ChannelID,
+			MessageID:     message.ID,
+			AuthorID:      message.Author.ID,
+			AuthorName:    message.Author.Username,
+			Reactors:      reactors,
+			InductedAt:    inductedAt,
+			HallChannelID: post.ChannelID,
+			HallMessageID: post.ID,
+		})
+		if err != nil {
+			log.Println("Failed to save induction: ", err)
+		}
+		// Fetched messages don't carry their guild
+		audit(s, auditEvent{
+			GuildID:   guildID,
+			ChannelID: message.ChannelID,
+			Type:      eventInduction,
+			UserID:    message.Author.ID,
+			UserName:  message.Author.Username,
+			Detail:    fmt.Sprintf("Inducted into %v with %v votes: %v", rule.Name, len(voters), jumpLink(guildID, message)),
+		})
+		return true
+	}
+	return false
+}
+
+func messageReactionRemove(s *discordgo.Session, event *discordgo.MessageReactionRemove) {
+	checkInductionVotes(s, event.ChannelID, event.MessageID, event.GuildID, event.Emoji.APIName())
+}
+
+func messageReactionRemoveAll(s *discordgo.Session, event *discordgo.MessageReactionRemoveAll) {
+	checkInductionVotes(s, event.ChannelID, event.MessageID, event.GuildID, "")
+}
+
+// checkInductionVotes revokes the message's induction if it no longer has enough votes for its
+// hall. An empty emoji means every reaction was removed.
+func checkInductionVotes(s *discordgo.Session, channelID, messageID, guildID, emoji string) {
+	record, ok := getInduction(channelID, messageID)
+	if !ok || record.Revoked {
+		return
+	}
+	rules := getHallRules(guildID)
+	i := findHallRule(rules, record.Hall)
+	if i < 0 || rules[i].Reversal == "" || rules[i].Reversal == reversalKeep {
+		return
+	}
+	rule := rules[i]
+	if emoji != "" && emoji != rule.Emoji {
+		return
+	}
+
+	message, err := s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		log.Printf("Message does not exist: %v", err)
+		return
+	}
+	voters := make([]*discordgo.User, 0)
+	if findReaction(message, rule.Emoji) != nil {
+		voters, err = getVoters(s, message, guildID, rule)
+		if err != nil {
+			log.Println("Failed to get reactors: ", err)
+			return
+		}
+	}
+	if len(voters) >= rule.Threshold {
+		return
+	}
+	revokeInduction(s, record, rule, revokedVotesNote)
+}
+
+func messageDelete(s *discordgo.Session, event *discordgo.MessageDelete) {
+	record, ok := getInduction(event.ChannelID, event.ID)
+	if !ok || record.Revoked {
+		return
 	}
-	// TODO: Can probably dry up with a map of emojis and functions to call if they meet the induction criteria
-	for _, reaction := range message.Reactions {
-		if reaction.Emoji.Name == "👌" {
-			if reaction.Count >= inductionMinCount {
-				reactors, err := getReactors(s, message, "👌")
-				if err != nil {
-					fmt.Println(err)
-				}
-				addToHallOfFame(s, message, reactors)
-				inductMessage(message.ChannelID, message.ID)
+	rules := getHallRules(event.GuildID)
+	i := findHallRule(rules, record.Hall)
+	if i < 0 {
+		return
+	}
+	revokeInduction(s, record, rules[i], revokedDeletedNote)
+}
+
+// revokeInduction applies the rule's reversal policy to the induction: the hall post is either
+// marked with note or deleted along with the record.
+func revokeInduction(s *discordgo.Session, record induction, rule inductionRule, note string) {
+	if rule.Reversal == reversalEdit || rule.Reversal == reversalRemove {
+		audit(s, auditEvent{
+			GuildID:   record.GuildID,
+			ChannelID: record.ChannelID,
+			Type:      eventRevocation,
+			UserID:    record.AuthorID,
+			UserName:  record.AuthorName,
+			Detail:    fmt.Sprintf("%v (%v from %v)", strings.Trim(note, "*"), rule.Reversal, rule.Name),
+		})
+	}
+	switch rule.Reversal {
+	case reversalEdit:
+		if record.HallMessageID != "" {
+			post, err := s.ChannelMessage(record.HallChannelID, record.HallMessageID)
+			if err == nil {
+				// The embed quotes the revoked message, so it goes and only the note is left
+				edit := discordgo.NewMessageEdit(post.ChannelID, post.ID).SetContent(revokedContent(post.Content, note))
+				edit.Embeds = []*discordgo.MessageEmbed{}
+				_, err = s.ChannelMessageEditComplex(edit)
 			}
-		} else if reaction.Emoji.Name == "💩" {
-			if reaction.Count >= inductionMinCount {
-				reactors, err := getReactors(s, message, "💩")
-				if err != nil {
-					fmt.Println(err)
-				}
-				addToHallOfShame(s, message, reactors)
-				inductMessage(message.ChannelID, message.ID)
+			if err != nil {
+				log.Println("Failed to edit hall post: ", err)
+			}
+		}
+		record.Revoked = true
+		err := inductMessage(record)
+		if err != nil {
+			log.Println("Failed to save induction: ", err)
+		}
+	case reversalRemove:
+		if record.HallMessageID != "" {
+			err := s.ChannelMessageDelete(record.HallChannelID, record.HallMessageID)
+			if err != nil {
+				log.Println("Failed to delete hall post: ", err)
 			}
 		}
+		err := removeInduction(record)
+		if err != nil {
+			log.Println("Failed to remove induction: ", err)
+		}
 	}
 }
 
-func getReactors(s *discordgo.Session, message *discordgo.Message, emoji string) ([]string, error) {
-	reactors := make([]string, 0)
-	users, err := s.MessageReactions(message.ChannelID, message.ID, emoji, reactorCount)
+// getVoters returns the users whose reactions count towards the rule.
+func getVoters(s *discordgo.Session, message *discordgo.Message, guildID string, rule inductionRule) ([]*discordgo.User, error) {
+	users, err := getReactors(s, message, rule.Emoji)
 	if err != nil {
 		return nil, err
 	}
-	for _, user := range users {
-		reactors = append(reactors, user.Username)
+	isNewMember := func(userID string) bool {
+		return isRecentMember(s, guildID, userID)
 	}
-	return reactors, nil
+	return filterVoters(users, message.Author.ID, rule, isNewMember), nil
 }
 
-// commandResult contains the result of whatever resolving a command. It allows
-// us to control the bot sending text or audio and/or deleting user messages.
-type commandResult struct {
-	resp          string
-	audio         [][]byte
-	deleteUserMsg bool
-}
-
-func resolveCommand(cmd interface{}) commandResult {
-	var (
-		cmdResult commandResult
-		err       error
-	)
-	// ToDo: Make this default either via constructor or some Go funcctionality
-	cmdResult.deleteUserMsg = true
-	switch cmd.(type) {
-	case ripCommand:
-		err = ripSound(cmd.(ripCommand))
-		cmdResult.resp = "Sound successfully created!"
-	case playCommand:
-		cmdResult.audio, err = playSound(cmd.(playCommand))
-	case listCommand:
-		cmdResult.resp, err = listSounds(cmd.(listCommand))
-	case messageCommand:
-		if containsBannedContent(cmd.(messageCommand)) {
-			cmdResult.resp = "That's banned content."
+// getReactors pages through everyone who reacted to the message with the emoji.
+func getReactors(s *discordgo.Session, message *discordgo.Message, emoji string) ([]*discordgo.User, error) {
+	reactors := make([]*discordgo.User, 0)
+	after := ""
+	for {
+		users, err := s.MessageReactions(message.ChannelID, message.ID, emoji, reactorPageSize, "", after)
+		if err != nil {
+			return nil, err
 		}
-		cmdResult.deleteUserMsg = false
-	default:
-		log.Printf("Parsing broken: %+v", cmd)
+		reactors = append(reactors, users...)
+		if len(users) < reactorPageSize {
+			return reactors, nil
+		}
+		after = users[len(users)-1].ID
 	}
+}
 
+// isRecentMember reports whether the user joined the guild less than HALL_MIN_MEMBER_AGE ago.
+func isRecentMember(s *discordgo.Session, guildID, userID string) bool {
+	if hallMinMemberAge <= 0 {
+		return false
+	}
+	member, err := s.State.Member(guildID, userID)
 	if err != nil {
-		cmdResult.resp = err.Error()
+		member, err = s.GuildMember(guildID, userID)
 	}
-	return cmdResult
+	if err != nil {
+		log.Println("Couldn't get guild member: ", err)
+		return false
+	}
+	return time.Since(member.JoinedAt) < hallMinMemberAge
 }
 
 func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
@@ -167,39 +341,91 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 
 	cmd, err := parseMsg(m.Content)
 	if err != nil {
-		s.ChannelMessageSend(m.ChannelID, err.Error())
+		s.ChannelMessageSend(m.ChannelID, userMessage(err))
 		return
 	}
 
-	cmdResult := resolveCommand(cmd)
+	ctx := newMessageContext(s, m.Message)
+	cmdResult := resolveCommand(ctx, cmd)
 
-	if len(cmdResult.audio) > 0 {
-		err = pipeOpusToDiscord(cmdResult.audio, s, m)
+	if len(cmdResult.sounds) > 0 {
+		err = queueSounds(ctx, cmdResult.sounds)
 		if err != nil {
-			s.ChannelMessageSend(m.ChannelID, err.Error())
+			logError("Queueing "+queuedNames(cmdResult.sounds), err)
+			s.ChannelMessageSend(m.ChannelID, userMessage(err))
+		} else {
+			recordPlays(cmdResult.sounds)
+			if cmdResult.announce && cmdResult.resp == "" {
+				cmdResult.resp = "Queued " + queuedNames(cmdResult.sounds)
+			}
 		}
 	}
-	if cmdResult.deleteUserMsg {
-		deleteMessage(s, m.Message)
+	if cmdResult.file != nil {
+		_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{Files: []*discordgo.File{cmdResult.file}})
+		if err != nil {
+			log.Println("Failed to upload file: ", err)
+			s.ChannelMessageSend(m.ChannelID, "Couldn't upload the file")
+		}
+	}
+	if !cmdResult.keepUserMsg {
+		err = deleteMessage(s, m.Message)
+		// Commands were already audited and deleting them is just cleanup, only messages a filter
+		// removed get a delete event
+		if _, ok := cmd.(messageCommand); ok && err == nil {
+			auditMessage(s, m.Message, eventDelete, m.Content)
+		}
 	}
 	if len(cmdResult.resp) > 0 {
-		msg, _ := s.ChannelMessageSend(m.ChannelID, cmdResult.resp)
-		delayedDeleteMessage(s, msg)
+		msgs := make([]*discordgo.Message, 0)
+		for _, chunk := range splitMessage(cmdResult.resp, maxMessageLength) {
+			msg, err := s.ChannelMessageSend(m.ChannelID, chunk)
+			if err != nil {
+				log.Println("Failed to send response: ", err)
+				continue
+			}
+			msgs = append(msgs, msg)
+		}
+		delayedDeleteMessage(s, msgs...)
 	}
 }
 
-func containsBannedContent(messageCmd messageCommand) bool {
-	re := regexp.MustCompile(censorRegex)
-	if re.FindIndex([]byte(messageCmd.content)) != nil {
-		return true
+// isAdmin reports whether the author of the message has the admin role. If ADMIN_ROLE_ID
+// isn't set we fall back to Discord's administrator permission.
+func isAdmin(s *discordgo.Session, m *discordgo.Message) bool {
+	if adminRoleID == "" {
+		perms, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+		if err != nil {
+			log.Println("Couldn't get user permissions: ", err)
+			return false
+		}
+		return perms&discordgo.PermissionAdministrator != 0
+	}
+
+	member := m.Member
+	if member == nil {
+		var err error
+		member, err = s.GuildMember(m.GuildID, m.Author.ID)
+		if err != nil {
+			log.Println("Couldn't get guild member: ", err)
+			return false
+		}
+	}
+	for _, role := range member.Roles {
+		if role == adminRoleID {
+			return true
+		}
 	}
 	return false
 }
 
-func deleteMessage(s *discordgo.Session, m *discordgo.Message) {
-	s.ChannelMessageDelete(m.ChannelID, m.ID)
-
+func deleteMessage(s *discordgo.Session, m *discordgo.Message) error {
+	err := s.ChannelMessageDelete(m.ChannelID, m.ID)
+	if err != nil {
+		log.Println("Failed to delete message: ", err)
+	}
+	return err
 }
+
 func delayedDeleteMessage(s *discordgo.Session, messages ...*discordgo.Message) {
 	time.Sleep(deleteDelay)
 	for _, message := range messages {
@@ -207,33 +433,34 @@ func delayedDeleteMessage(s *discordgo.Session, messages ...*discordgo.Message)
 	}
 }
 
-func pipeOpusToDiscord(opusFrames [][]byte, s *discordgo.Session, m *discordgo.MessageCreate) error {
-	vs, err := findUserVoiceState(s, m.Author.ID)
-	if err != nil {
-		return errors.New("Couldn't find user voice channel")
-	}
-	dgv, err := s.ChannelVoiceJoin(m.GuildID, vs.ChannelID, false, true)
-	if err != nil {
-		return errors.New("Couldn't join voice channel")
-	}
-	defer dgv.Disconnect()
-
-	err = dgv.Speaking(true)
-	if err != nil {
-		log.Println("Couldn't set speaking: ", err)
-	}
-
-	defer func() {
-		err := dgv.Speaking(false)
-		if err != nil {
-			log.Println("Couldn't stop speaking: ", err)
+// splitMessage breaks text into chunks of at most limit bytes, preferring to break between
+// lines, then after commas and then between words.
+func splitMessage(text string, limit int) []string {
+	chunks := make([]string, 0)
+	for len(text) > limit {
+		// The separator itself can fall just past the limit since it isn't kept
+		window := text[:limit+1]
+		cut := strings.LastIndex(window, "\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(window, ", ") + 1
 		}
-	}()
-
-	for _, byteArray := range opusFrames {
-		dgv.OpusSend <- byteArray
+		if cut <= 0 {
+			cut = strings.LastIndex(window, " ")
+		}
+		if cut <= 0 {
+			// No good place to break, just don't split a character in half
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
 	}
-	return nil
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
 }
 
 func findUserVoiceState(session *discordgo.Session, userid string) (*discordgo.VoiceState, error) {
@@ -247,49 +474,69 @@ func findUserVoiceState(session *discordgo.Session, userid string) (*discordgo.V
 	return nil, errors.New("Could not find user's voice state")
 }
 
-func addToHallOfFame(s *discordgo.Session, m *discordgo.Message, reactors []string) error {
-	ts, err := m.Timestamp.Parse()
+// addToHall posts the message into the rule's hall and returns the post.
+func addToHall(s *discordgo.Session, m *discordgo.Message, guildID string, rule inductionRule, voters []*discordgo.User) (*discordgo.Message, error) {
+	msgTxt := formatInduction(rule, m, usernames(voters))
+
+	channelName := ""
+	channel, err := s.State.Channel(m.ChannelID)
 	if err != nil {
-		log.Println("Discord messed up here: ", err.Error())
-		return err
+		channel, err = s.Channel(m.ChannelID)
+	}
+	if err == nil {
+		channelName = channel.Name
 	}
 
-	msgTxt := fmt.Sprintf("**Posted on %v by %v.**\n**Voted in by %v**\n\n%v", ts.Format("January 2, 2006"), m.Author.Username, strings.Join(reactors, ", "), m.Content)
-	_, err = s.ChannelMessageSend(hallOfFameChanID, msgTxt)
+	post, err := s.ChannelMessageSendComplex(rule.ChannelID, &discordgo.MessageSend{
+		Content: msgTxt,
+		Embed:   buildInductionEmbed(m, guildID, channelName),
+	})
 	if err != nil {
-		log.Println("Failed to create HoF message: ", err.Error())
-		return err
+		log.Printf("Failed to create %v message: %v", rule.Name, err.Error())
+		return nil, err
 	}
 
-	return nil
+	return post, nil
 }
 
-func addToHallOfShame(s *discordgo.Session, m *discordgo.Message, reactors []string) error {
-	ts, err := m.Timestamp.Parse()
-	if err != nil {
-		log.Println("Discord messed up here: ", err.Error())
-		return err
+// auditClip audits a newly created sound, if creating it worked.
+func auditClip(s *discordgo.Session, m *discordgo.Message, name string, err error) {
+	if err == nil {
+		auditMessage(s, m, eventClip, "Created "+name)
 	}
+}
 
-	msgTxt := fmt.Sprintf("**Posted in infamy on %v by %v.**\n**Voted in by %v**\n\n%v", ts.Format("January 2, 2006"), m.Author.Username, strings.Join(reactors, ", "), m.Content)
-	_, err = s.ChannelMessageSend(hallOfShameChanID, msgTxt)
-	if err != nil {
-		log.Println("Failed to create HoF message: ", err.Error())
-		return err
+// findAttachment returns the first attachment on the message with one of the extensions, or nil.
+func findAttachment(m *discordgo.Message, exts ...string) *discordgo.MessageAttachment {
+	for _, att := range m.Attachments {
+		name := strings.ToLower(att.Filename)
+		for _, ext := range exts {
+			if strings.HasSuffix(name, ext) {
+				return att
+			}
+		}
 	}
-
 	return nil
 }
 
-func initSoundDir() error {
-	_, err := os.Stat("sounds/")
-	if os.IsNotExist(err) {
-		err = os.Mkdir("sounds", os.ModePerm)
+func downloadAttachment(att *discordgo.MessageAttachment) ([]byte, error) {
+	if att.Size > maxAttachmentSize {
+		return nil, newUserError(codeTooLarge, fmt.Sprintf("Attachments can be at most %vMB", maxAttachmentSize>>20))
 	}
+	resp, err := http.Get(att.URL)
 	if err != nil {
-		return err
+		return nil, retryableError(codeDiscord, "Error downloading attachment", err)
 	}
-	return nil
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, retryableError(codeDiscord, "Error downloading attachment", fmt.Errorf("GET %v: %v", att.Filename, resp.Status))
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize))
+	if err != nil {
+		return nil, retryableError(codeDiscord, "Error downloading attachment", err)
+	}
+	return b, nil
 }
 
 func getUserBySubstring(s *discordgo.Session, name string) (*discordgo.Member, error) {
@@ -302,5 +549,5 @@ func getUserBySubstring(s *discordgo.Session, name string) (*discordgo.Member, e
 			return member, nil
 		}
 	}
-	return nil, errors.New("User not found")
+	return nil, newUserError(codeNotFound, "User not found")
 }
@@ -0,0 +1,30 @@
+package judgego
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitMessage(t *testing.T) {
+	assert.Equal(t, []string{"short"}, splitMessage("short", 10))
+	assert.Empty(t, splitMessage("", 10))
+
+	// Lines are kept whole, then lists are broken after commas, then words at spaces
+	assert.Equal(t, []string{"one\ntwo", "three"}, splitMessage("one\ntwo\nthree", 8))
+	assert.Equal(t, []string{"Sounds: a,", "bb, ccc,", "dddd"}, splitMessage("Sounds: a, bb, ccc, dddd", 10))
+	assert.Equal(t, []string{"hello", "there"}, splitMessage("hello there", 8))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, splitMessage("abcdefghij", 4))
+	assert.Equal(t, []string{"éé", "é"}, splitMessage("ééé", 5))
+
+	sounds := make([]string, 0)
+	for i := 0; i < 500; i++ {
+		sounds = append(sounds, "sound"+strings.Repeat("x", i%10))
+	}
+	chunks := splitMessage("Available Sounds: "+strings.Join(sounds, ", "), maxMessageLength)
+	assert.True(t, len(chunks) > 1)
+	for _, chunk := range chunks {
+		assert.True(t, len(chunk) <= maxMessageLength)
+	}
+}
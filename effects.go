@@ -0,0 +1,161 @@
+package judgego
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	normalizeFlag = "--normalize"
+	fadeInFlag    = "--fadein"
+	fadeOutFlag   = "--fadeout"
+	gainFlag      = "--gain"
+	speedFlag     = "--speed"
+	reverseFlag   = "--reverse"
+
+	// minSpeed and maxSpeed are the limits of a single ffmpeg atempo filter
+	minSpeed = 0.5
+	maxSpeed = 2.0
+	maxGain  = 20.0
+)
+
+// audioEffects contains the optional post-processing applied to a clip while converting it.
+type audioEffects struct {
+	normalize bool
+	fadeIn    time.Duration
+	fadeOut   time.Duration
+	gain      float64
+	speed     float64
+	reverse   bool
+}
+
+// parseEffectFlags parses flags like --normalize, --fadein 200ms, --gain -3dB into audioEffects.
+func parseEffectFlags(tokens []string) (audioEffects, error) {
+	effects := audioEffects{}
+	for i := 0; i < len(tokens); i++ {
+		flag := strings.ToLower(tokens[i])
+		switch flag {
+		case normalizeFlag:
+			effects.normalize = true
+			continue
+		case reverseFlag:
+			effects.reverse = true
+			continue
+		case fadeInFlag, fadeOutFlag, gainFlag, speedFlag:
+		default:
+			return effects, invalidInput("Unknown option " + tokens[i])
+		}
+
+		if i+1 >= len(tokens) {
+			return effects, invalidInput(flag + " needs a value")
+		}
+		i++
+		value := tokens[i]
+
+		var err error
+		switch flag {
+		case fadeInFlag:
+			effects.fadeIn, err = parseFadeDuration(value)
+		case fadeOutFlag:
+			effects.fadeOut, err = parseFadeDuration(value)
+		case gainFlag:
+			effects.gain, err = parseGain(value)
+		case speedFlag:
+			effects.speed, err = parseSpeed(value)
+		}
+		if err != nil {
+			return effects, err
+		}
+	}
+	return effects, nil
+}
+
+func parseFadeDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, invalidInput("Invalid fade length " + value + ". Use a form like 200ms or 1s")
+	}
+	return d, nil
+}
+
+func parseGain(value string) (float64, error) {
+	trimmed := value
+	if strings.HasSuffix(strings.ToLower(trimmed), "db") {
+		trimmed = trimmed[:len(trimmed)-2]
+	}
+	gain, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || gain < -maxGain || gain > maxGain {
+		return 0, invalidInput(fmt.Sprintf("Invalid gain %v. Use a form like -3dB between -%vdB and %vdB", value, maxGain, maxGain))
+	}
+	return gain, nil
+}
+
+func parseSpeed(value string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(value), "x"), 64)
+	if err != nil || speed < minSpeed || speed > maxSpeed {
+		return 0, invalidInput(fmt.Sprintf("Invalid speed %v. Use a number between %v and %v", value, minSpeed, maxSpeed))
+	}
+	return speed, nil
+}
+
+// filter builds the ffmpeg -af filter chain for the effects. duration is the length of the
+// input clip in seconds, it's needed to know where the fade out starts. Returns an empty
+// string if there's nothing to do.
+func (e audioEffects) filter(duration float64) string {
+	filters := make([]string, 0)
+	if e.speed != 0 && e.speed != 1 {
+		filters = append(filters, "atempo="+formatFloat(e.speed))
+		duration = duration / e.speed
+	}
+	if e.reverse {
+		filters = append(filters, "areverse")
+	}
+	// Gain goes after loudnorm, which would otherwise undo it
+	if e.normalize {
+		filters = append(filters, "loudnorm")
+	}
+	if e.gain != 0 {
+		filters = append(filters, "volume="+formatFloat(e.gain)+"dB")
+	}
+	if e.fadeIn > 0 {
+		filters = append(filters, "afade=t=in:st=0:d="+formatFloat(e.fadeIn.Seconds()))
+	}
+	if e.fadeOut > 0 {
+		start := duration - e.fadeOut.Seconds()
+		if start < 0 {
+			start = 0
+		}
+		filters = append(filters, "afade=t=out:st="+formatFloat(start)+":d="+formatFloat(e.fadeOut.Seconds()))
+	}
+	return strings.Join(filters, ",")
+}
+
+// String formats the effects back into the flags they were parsed from.
+func (e audioEffects) String() string {
+	flags := make([]string, 0)
+	if e.normalize {
+		flags = append(flags, normalizeFlag)
+	}
+	if e.fadeIn > 0 {
+		flags = append(flags, fadeInFlag+" "+e.fadeIn.String())
+	}
+	if e.fadeOut > 0 {
+		flags = append(flags, fadeOutFlag+" "+e.fadeOut.String())
+	}
+	if e.gain != 0 {
+		flags = append(flags, gainFlag+" "+formatFloat(e.gain)+"dB")
+	}
+	if e.speed != 0 && e.speed != 1 {
+		flags = append(flags, speedFlag+" "+formatFloat(e.speed))
+	}
+	if e.reverse {
+		flags = append(flags, reverseFlag)
+	}
+	return strings.Join(flags, " ")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
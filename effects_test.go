@@ -0,0 +1,73 @@
+package judgego
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEffectFlags(t *testing.T) {
+	effects, err := parseEffectFlags(strings.Split("--normalize --fadein 200ms --fadeout 300ms --gain -3dB --speed 1.5 --reverse", " "))
+
+	assert.Nil(t, err)
+	assert.Equal(t, audioEffects{
+		normalize: true,
+		fadeIn:    200 * time.Millisecond,
+		fadeOut:   300 * time.Millisecond,
+		gain:      -3,
+		speed:     1.5,
+		reverse:   true,
+	}, effects)
+	assert.Equal(t, "--normalize --fadein 200ms --fadeout 300ms --gain -3dB --speed 1.5 --reverse", effects.String())
+}
+
+var parseEffectFlagsFailTable = []string{
+	"--fadein",
+	"--fadein soon",
+	"--gain loud",
+	"--gain 90dB",
+	"--speed 10",
+	"--echo",
+}
+
+func TestParseEffectFlagsFail(t *testing.T) {
+	for _, flags := range parseEffectFlagsFailTable {
+		_, err := parseEffectFlags(strings.Split(flags, " "))
+		assert.NotNil(t, err, flags)
+	}
+}
+
+func TestEffectsFilter(t *testing.T) {
+	assert.Equal(t, "", audioEffects{}.filter(5))
+
+	effects := audioEffects{speed: 2, gain: -3, fadeIn: 200 * time.Millisecond, fadeOut: 500 * time.Millisecond}
+	assert.Equal(t, "atempo=2,volume=-3dB,afade=t=in:st=0:d=0.2,afade=t=out:st=2:d=0.5", effects.filter(5))
+
+	// Gain is applied after normalizing so it isn't undone
+	assert.Equal(t, "loudnorm,volume=2dB", audioEffects{normalize: true, gain: 2}.filter(5))
+}
+
+func TestParseRipCmdWithEffects(t *testing.T) {
+	parsedRipCmd, err := parseRipCmd("$rip testName https://www.youtube.com/watch?v=dFuUCpBbbHw 0m1s 0m10s --normalize --gain 2dB")
+
+	assert.Nil(t, err)
+	assert.Equal(t, audioEffects{normalize: true, gain: 2}, parsedRipCmd.effects)
+}
+
+func TestFfmpegArgsSpeedReverse(t *testing.T) {
+	ripCmd, err := parseRipCmd("$rip testName https://www.youtube.com/watch?v=dFuUCpBbbHw 0m10s 0m15s --speed 2 --reverse")
+	assert.Nil(t, err)
+
+	args := ffmpegArgs(ripCmd.start, ripCmd.duration, ripCmd.effects.filter(5))
+
+	// The clip is cut out of the input before any filter sees it
+	assert.Equal(t, []string{
+		"-ss", "10", "-t", "5", "-i", "pipe:0",
+		"-af", ripCmd.effects.filter(5),
+		"-f", "s16le", "-ar", "48000", "-ac", "2", "pipe:1",
+	}, args)
+	assert.Contains(t, ripCmd.effects.filter(5), "atempo=2")
+	assert.Contains(t, ripCmd.effects.filter(5), "areverse")
+}
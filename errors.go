@@ -0,0 +1,87 @@
+package judgego
+
+import (
+	"errors"
+	"log"
+)
+
+// Error codes group userErrors by what went wrong.
+const (
+	codeInvalidInput = "invalid_input"
+	codeNotFound     = "not_found"
+	codeConflict     = "conflict"
+	codePermission   = "permission"
+	codeTooLarge     = "too_large"
+	codeStorage      = "storage"
+	codeMedia        = "media"
+	codeDiscord      = "discord"
+)
+
+// genericErrorMessage is what users are told about errors that aren't userErrors
+const genericErrorMessage = "Something went wrong"
+
+// userError is an error with a message that's safe to show in chat. The cause is what actually
+// went wrong, it only ever makes it into the logs.
+type userError struct {
+	msg       string
+	cause     error
+	code      string
+	retryable bool
+}
+
+func (e *userError) Error() string {
+	if e.cause == nil {
+		return e.msg
+	}
+	return e.msg + ": " + e.cause.Error()
+}
+
+func (e *userError) Unwrap() error {
+	return e.cause
+}
+
+// newUserError is for a problem with what the user asked for. There's no cause to log.
+func newUserError(code, msg string) *userError {
+	return &userError{msg: msg, code: code}
+}
+
+// invalidInput is a newUserError for input that doesn't make sense.
+func invalidInput(msg string) *userError {
+	return newUserError(codeInvalidInput, msg)
+}
+
+// wrapError hides cause behind msg.
+func wrapError(code, msg string, cause error) *userError {
+	return &userError{msg: msg, cause: cause, code: code}
+}
+
+// retryableError is wrapError for failures that might go away if the user tries again, like
+// network hiccups.
+func retryableError(code, msg string, cause error) *userError {
+	return &userError{msg: msg, cause: cause, code: code, retryable: true}
+}
+
+// userMessage is what to tell the user about err.
+func userMessage(err error) string {
+	var uerr *userError
+	if !errors.As(err, &uerr) {
+		return genericErrorMessage
+	}
+	if uerr.retryable {
+		return uerr.msg + ". Try again in a bit"
+	}
+	return uerr.msg
+}
+
+// logError logs the cause of err along with what was going on. userErrors without a cause are
+// the user's doing and aren't logged.
+func logError(context string, err error) {
+	var uerr *userError
+	if !errors.As(err, &uerr) {
+		log.Printf("%v: %v", context, err)
+		return
+	}
+	if uerr.cause != nil {
+		log.Printf("%v [%v]: %v", context, uerr.code, err)
+	}
+}
@@ -0,0 +1,29 @@
+package judgego
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserMessage(t *testing.T) {
+	cause := errors.New("AccessDenied: bucket policy")
+
+	assert.Equal(t, "Sound not found", userMessage(newUserError(codeNotFound, "Sound not found")))
+	assert.Equal(t, "Error saving filters", userMessage(wrapError(codeStorage, "Error saving filters", cause)))
+	assert.Equal(t, "Error saving filters. Try again in a bit", userMessage(retryableError(codeStorage, "Error saving filters", cause)))
+	assert.Equal(t, genericErrorMessage, userMessage(cause))
+
+	// The user message survives being wrapped again
+	wrapped := fmt.Errorf("resolving: %w", invalidInput("Invalid timestamp"))
+	assert.Equal(t, "Invalid timestamp", userMessage(wrapped))
+}
+
+func TestUserErrorCause(t *testing.T) {
+	err := wrapError(codeMedia, "Error converting audio", errStreamDone)
+	assert.True(t, errors.Is(err, errStreamDone))
+	assert.Equal(t, "Error converting audio: "+errStreamDone.Error(), err.Error())
+	assert.Nil(t, errors.Unwrap(invalidInput("Invalid timestamp")))
+}
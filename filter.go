@@ -0,0 +1,336 @@
+package judgego
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// moderationDir is the local directory moderation settings are stored in when S3 persistence is off
+	moderationDir = "moderation"
+	// moderationFilePrefix is the bucket prefix moderation settings are stored under
+	moderationFilePrefix = "moderation/"
+	filterRulesFilename  = "filterRules.json"
+
+	// defaultFilterPattern is the original hardcoded censor regex, used by guilds without their own filters
+	defaultFilterPattern = `\b(wakeley|wakefest)\b`
+	// defaultFilterTimeout is how long members are timed out for when FILTER_TIMEOUT isn't set
+	defaultFilterTimeout = 10 * time.Minute
+	// maxFilterTimeout is the longest timeout Discord allows
+	maxFilterTimeout = 28 * day
+
+	filterWord  = "word"
+	filterRegex = "regex"
+
+	actionWarn    = "warn"
+	actionDelete  = "delete"
+	actionTimeout = "timeout"
+	actionLog     = "log"
+
+	bannedContentWarning = "That's banned content."
+)
+
+var filterActions = []string{actionWarn, actionDelete, actionTimeout, actionLog}
+
+var filterTimeout = parseFilterTimeout(os.Getenv("FILTER_TIMEOUT"))
+
+// homoglyphs maps lookalike letters to the plain ASCII letter they imitate. Accents are
+// folded here as well since combining marks are only stripped when they're separate runes.
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// Latin with accents
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ç': 'c', 'è': 'e', 'é': 'e',
+	'ê': 'e', 'ë': 'e', 'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ı': 'i', 'ñ': 'n', 'ò': 'o',
+	'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y', 'ɡ': 'g',
+}
+
+// filterRule is a word or regex that isn't allowed in messages and what the bot does about it.
+type filterRule struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Pattern string   `json:"pattern"`
+	Actions []string `json:"actions"`
+
+	matcher *regexp.Regexp
+}
+
+// filterRuleSet holds the filters of every guild that has customized them. Guilds without
+// an entry use defaultFilterRules.
+type filterRuleSet struct {
+	sync.RWMutex
+	m map[string][]filterRule
+}
+
+var filterRules = filterRuleSet{m: make(map[string][]filterRule)}
+
+var defaultFilterRules = mustCompileFilters([]filterRule{
+	{Name: "default", Type: filterRegex, Pattern: defaultFilterPattern, Actions: []string{actionWarn}},
+})
+
+// moderationStore is where moderation settings are persisted. It's selected once in Start.
+var moderationStore SoundStore
+
+// newModerationStore returns the store for moderation settings selected by the S3_PERSISTENCE env variable.
+func newModerationStore() (SoundStore, error) {
+	if s3Persistence == "true" {
+		return newS3Store(bucketName, moderationFilePrefix)
+	}
+	return newLocalStore(moderationDir)
+}
+
+func parseFilterTimeout(value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return defaultFilterTimeout
+	}
+	if d > maxFilterTimeout {
+		return maxFilterTimeout
+	}
+	return d
+}
+
+// normalizeText lowercases text, drops zero width and combining characters and swaps
+// homoglyphs and fullwidth letters for ASCII so filters can't be dodged with lookalikes.
+func normalizeText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		// Fullwidth forms of ASCII
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		r = unicode.ToLower(r)
+		if plain, ok := homoglyphs[r]; ok {
+			r = plain
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// compile builds the rule's matcher. Words match whole words, regexes are case insensitive.
+// Both are run against normalized text.
+func (f *filterRule) compile() error {
+	pattern := f.Pattern
+	if f.Type == filterWord {
+		pattern = `\b` + regexp.QuoteMeta(normalizeText(f.Pattern)) + `\b`
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return err
+	}
+	f.matcher = re
+	return nil
+}
+
+func mustCompileFilters(rules []filterRule) []filterRule {
+	for i := range rules {
+		err := rules[i].compile()
+		if err != nil {
+			panic(err)
+		}
+	}
+	return rules
+}
+
+func getFilterRules(guildID string) []filterRule {
+	filterRules.RLock()
+	defer filterRules.RUnlock()
+	rules, ok := filterRules.m[guildID]
+	if !ok {
+		return defaultFilterRules
+	}
+	return append([]filterRule{}, rules...)
+}
+
+func loadFilterRules() error {
+	b, err := moderationStore.Get(filterRulesFilename)
+	if err == errNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[string][]filterRule)
+	err = json.Unmarshal(b, &rules)
+	if err != nil {
+		return err
+	}
+	for guildID, guildRules := range rules {
+		for i := range guildRules {
+			err = guildRules[i].compile()
+			if err != nil {
+				return fmt.Errorf("filter %v in %v: %v", guildRules[i].Name, guildID, err)
+			}
+		}
+	}
+
+	filterRules.Lock()
+	filterRules.m = rules
+	filterRules.Unlock()
+	return nil
+}
+
+// updateFilterRules applies update to the guild's filters and saves the result.
+func updateFilterRules(guildID string, update func([]filterRule) ([]filterRule, error)) error {
+	filterRules.Lock()
+	defer filterRules.Unlock()
+	rules, ok := filterRules.m[guildID]
+	if !ok {
+		rules = append([]filterRule{}, defaultFilterRules...)
+	}
+	rules, err := update(rules)
+	if err != nil {
+		return err
+	}
+	filterRules.m[guildID] = rules
+
+	b, err := json.Marshal(filterRules.m)
+	if err == nil {
+		err = moderationStore.Put(filterRulesFilename, b)
+	}
+	if err != nil {
+		return retryableError(codeStorage, "Error saving filters", err)
+	}
+	return nil
+}
+
+func findFilterRule(rules []filterRule, name string) int {
+	for i, rule := range rules {
+		if strings.EqualFold(rule.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchFilters returns every filter the content trips.
+func matchFilters(rules []filterRule, content string) []filterRule {
+	normalized := normalizeText(content)
+	matched := make([]filterRule, 0)
+	for _, rule := range rules {
+		if rule.matcher != nil && rule.matcher.MatchString(normalized) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+func hasAction(rules []filterRule, action string) bool {
+	for _, rule := range rules {
+		for _, a := range rule.Actions {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isFilterAction(action string) bool {
+	for _, a := range filterActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// moderateMessage runs the message past the guild's filters, takes the timeout action and
+// audits the hit, with the message itself if the log action is set. It returns the warning to
+// reply with, if any, and whether the message should be deleted.
+func moderateMessage(s *discordgo.Session, m *discordgo.Message) (string, bool) {
+	matched := matchFilters(getFilterRules(m.GuildID), m.Content)
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	if hasAction(matched, actionTimeout) {
+		timeoutMember(s, m.GuildID, m.Author.ID)
+	}
+	names := make([]string, 0, len(matched))
+	for _, rule := range matched {
+		names = append(names, rule.Name)
+	}
+	detail := "Caught by " + strings.Join(names, ", ")
+	if hasAction(matched, actionLog) {
+		detail += ": " + m.Content
+	}
+	auditMessage(s, m, eventFilter, detail)
+
+	warning := ""
+	if hasAction(matched, actionWarn) {
+		warning = bannedContentWarning
+	}
+	return warning, hasAction(matched, actionDelete)
+}
+
+// timeoutMember times the user out for filterTimeout from now. Discord lifts the timeout itself,
+// so it survives restarts, and a later hit just pushes the end back.
+func timeoutMember(s *discordgo.Session, guildID, userID string) {
+	until := time.Now().Add(filterTimeout)
+	err := s.GuildMemberTimeout(guildID, userID, &until)
+	if err != nil {
+		log.Println("Failed to time out member: ", err)
+	}
+}
+
+func resolveFilterCommand(filterCmd filterCommand, guildID string) (string, error) {
+	switch filterCmd.action {
+	case "add":
+		err := updateFilterRules(guildID, func(rules []filterRule) ([]filterRule, error) {
+			if i := findFilterRule(rules, filterCmd.rule.Name); i >= 0 {
+				rules[i] = filterCmd.rule
+				return rules, nil
+			}
+			return append(rules, filterCmd.rule), nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Filter " + filterCmd.rule.Name + " saved!", nil
+	case "remove":
+		err := updateFilterRules(guildID, func(rules []filterRule) ([]filterRule, error) {
+			i := findFilterRule(rules, filterCmd.rule.Name)
+			if i < 0 {
+				return nil, newUserError(codeNotFound, "No filter named "+filterCmd.rule.Name)
+			}
+			return append(rules[:i], rules[i+1:]...), nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Filter " + filterCmd.rule.Name + " removed!", nil
+	default:
+		return listFilterRules(getFilterRules(guildID)), nil
+	}
+}
+
+func listFilterRules(rules []filterRule) string {
+	if len(rules) == 0 {
+		return "No filters are set up."
+	}
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, fmt.Sprintf("**%v** - %v `%v` → %v", rule.Name, rule.Type, rule.Pattern, strings.Join(rule.Actions, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,99 @@
+package judgego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeText(t *testing.T) {
+	assert.Equal(t, "wakeley", normalizeText("WAKELEY"))
+	// Cyrillic а and е, a zero width space and a combining acute accent
+	assert.Equal(t, "wakeley", normalizeText("wаk​eléy"))
+	assert.Equal(t, "wakeley", normalizeText("ｗａｋｅｌｅｙ"))
+	assert.Equal(t, "wakefest", normalizeText("wakéfest"))
+}
+
+func TestMatchFilters(t *testing.T) {
+	assert.Len(t, matchFilters(defaultFilterRules, "hey WAKELEY"), 1)
+	assert.Len(t, matchFilters(defaultFilterRules, "hey wаkеlеy"), 1)
+	assert.Empty(t, matchFilters(defaultFilterRules, "wakeleys are fine"))
+
+	rules := mustCompileFilters([]filterRule{
+		{Name: "dots", Type: filterWord, Pattern: "a.b", Actions: []string{actionDelete}},
+		{Name: "digits", Type: filterRegex, Pattern: `\d{4}`, Actions: []string{actionLog, actionWarn}},
+	})
+	matched := matchFilters(rules, "A.B 1234")
+	assert.Len(t, matched, 2)
+	assert.True(t, hasAction(matched, actionDelete))
+	assert.False(t, hasAction(matched, actionTimeout))
+	// Words are matched literally
+	assert.Empty(t, matchFilters(rules[:1], "axb"))
+}
+
+func TestParseFilterCmd(t *testing.T) {
+	filterCmd, err := parseFilterCmd("$filter add slur word Badword delete Log")
+	assert.Nil(t, err)
+	assert.Equal(t, "add", filterCmd.action)
+	assert.Equal(t, "slur", filterCmd.rule.Name)
+	assert.Equal(t, filterWord, filterCmd.rule.Type)
+	assert.Equal(t, "Badword", filterCmd.rule.Pattern)
+	assert.Equal(t, []string{actionDelete, actionLog}, filterCmd.rule.Actions)
+	assert.NotNil(t, filterCmd.rule.matcher)
+
+	filterCmd, err = parseFilterCmd(`$filter add phone regex \d{3}-\d{4}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{actionWarn}, filterCmd.rule.Actions)
+
+	filterCmd, err = parseFilterCmd("$filter")
+	assert.Nil(t, err)
+	assert.Equal(t, "list", filterCmd.action)
+
+	for _, msg := range []string{"$filter add x word", "$filter add x glob y", "$filter add x regex (", "$filter add x word y ban", "$filter remove", "$filter clear"} {
+		_, err = parseFilterCmd(msg)
+		assert.NotNil(t, err, msg)
+	}
+}
+
+func TestResolveFilterCommand(t *testing.T) {
+	oldStore := moderationStore
+	moderationStore = newMemoryStore()
+	defer func() { moderationStore = oldStore }()
+	filterRules.Lock()
+	oldRules := filterRules.m
+	filterRules.m = make(map[string][]filterRule)
+	filterRules.Unlock()
+	defer func() {
+		filterRules.Lock()
+		filterRules.m = oldRules
+		filterRules.Unlock()
+	}()
+
+	filterCmd, _ := parseFilterCmd("$filter add slur word badword delete")
+	_, err := resolveFilterCommand(filterCmd, "g")
+	assert.Nil(t, err)
+	rules := getFilterRules("g")
+	assert.Len(t, rules, 2)
+	assert.Len(t, matchFilters(rules, "BADWORD"), 1)
+
+	_, err = resolveFilterCommand(filterCommand{action: "remove", rule: filterRule{Name: "default"}}, "g")
+	assert.Nil(t, err)
+	assert.Empty(t, matchFilters(getFilterRules("g"), "wakeley"))
+	// Other guilds keep the default
+	assert.Len(t, matchFilters(getFilterRules("other"), "wakeley"), 1)
+
+	// Saved rules compile again on load
+	filterRules.Lock()
+	filterRules.m = make(map[string][]filterRule)
+	filterRules.Unlock()
+	assert.Nil(t, loadFilterRules())
+	assert.Len(t, matchFilters(getFilterRules("g"), "badword"), 1)
+}
+
+func TestParseFilterTimeout(t *testing.T) {
+	assert.Equal(t, 30*time.Minute, parseFilterTimeout("30m"))
+	assert.Equal(t, defaultFilterTimeout, parseFilterTimeout(""))
+	assert.Equal(t, defaultFilterTimeout, parseFilterTimeout("-5m"))
+	assert.Equal(t, maxFilterTimeout, parseFilterTimeout("1000h"))
+}
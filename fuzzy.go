@@ -0,0 +1,136 @@
+package judgego
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the most sounds offered when a name could mean more than one
+const maxSuggestions = 5
+
+// How closely a sound matches what was typed, best first.
+const (
+	matchExact = iota
+	matchFolded
+	matchPrefix
+	matchSubstring
+	matchFuzzy
+)
+
+// soundMatch is a sound that matches what was typed.
+type soundMatch struct {
+	name string
+	kind int
+	// distance is the edit distance for fuzzy matches
+	distance int
+}
+
+func (m soundMatch) better(o soundMatch) bool {
+	if m.kind != o.kind {
+		return m.kind < o.kind
+	}
+	if m.distance != o.distance {
+		return m.distance < o.distance
+	}
+	return m.name < o.name
+}
+
+func (m soundMatch) ties(o soundMatch) bool {
+	return m.kind == o.kind && m.distance == o.distance
+}
+
+// rankSounds returns every sound that matches the query, best match first. Sounds match
+// exactly, ignoring case, by starting with or containing the query, or by being within a few
+// typos of it.
+func rankSounds(sounds []string, query string) []soundMatch {
+	folded := strings.ToLower(query)
+	maxDistance := maxEditDistance(query)
+	matches := make([]soundMatch, 0)
+	for _, sound := range sounds {
+		lower := strings.ToLower(sound)
+		switch {
+		case sound == query:
+			matches = append(matches, soundMatch{name: sound, kind: matchExact})
+		case lower == folded:
+			matches = append(matches, soundMatch{name: sound, kind: matchFolded})
+		case strings.HasPrefix(lower, folded):
+			matches = append(matches, soundMatch{name: sound, kind: matchPrefix})
+		case strings.Contains(lower, folded):
+			matches = append(matches, soundMatch{name: sound, kind: matchSubstring})
+		default:
+			if d := editDistance(lower, folded); d <= maxDistance {
+				matches = append(matches, soundMatch{name: sound, kind: matchFuzzy, distance: d})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].better(matches[j])
+	})
+	return matches
+}
+
+// matchSound picks the sound the name most likely refers to. If several sounds are equally
+// likely none is picked and they're returned as suggestions instead.
+func matchSound(sounds []string, name string) (string, []string) {
+	matches := rankSounds(sounds, name)
+	if len(matches) == 0 {
+		return "", nil
+	}
+	best := matches[0]
+	if best.kind == matchExact || len(matches) == 1 || !matches[1].ties(best) {
+		return best.name, nil
+	}
+
+	suggestions := make([]string, 0, maxSuggestions)
+	for _, match := range matches {
+		if !match.ties(best) || len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, match.name)
+	}
+	return "", suggestions
+}
+
+// maxEditDistance is how many typos a name of the query's length can have and still match.
+func maxEditDistance(query string) int {
+	d := 1 + len([]rune(query))/4
+	if d > 3 {
+		return 3
+	}
+	return d
+}
+
+// editDistance is the number of insertions, deletions, substitutions and swaps of adjacent
+// characters it takes to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Only the last three rows are needed, the one before last for swaps
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,51 @@
+package judgego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	var tests = []struct {
+		a, b string
+		out  int
+	}{
+		{"", "", 0},
+		{"horn", "horn", 0},
+		{"horn", "hron", 1},
+		{"horn", "hor", 1},
+		{"horn", "thorn", 1},
+		{"horn", "barn", 2},
+		{"dethklok", "", 8},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.out, editDistance(test.a, test.b), test.a+" "+test.b)
+		assert.Equal(t, test.out, editDistance(test.b, test.a), test.b+" "+test.a)
+	}
+}
+
+func TestMatchSound(t *testing.T) {
+	sounds := []string{"airhorn", "bruh", "dethklok", "dethmetal", "Mail", "mail", "sadtrombone"}
+
+	var tests = []struct {
+		in          string
+		match       string
+		suggestions []string
+	}{
+		{"mail", "mail", nil},
+		{"MAIL", "", []string{"Mail", "mail"}},
+		{"BRUH", "bruh", nil},
+		{"sad", "sadtrombone", nil},
+		{"deth", "", []string{"dethklok", "dethmetal"}},
+		{"trombone", "sadtrombone", nil},
+		{"brhu", "bruh", nil},
+		{"dethklk", "dethklok", nil},
+		{"xylophone", "", nil},
+	}
+	for _, test := range tests {
+		match, suggestions := matchSound(sounds, test.in)
+		assert.Equal(t, test.match, match, test.in)
+		assert.Equal(t, test.suggestions, suggestions, test.in)
+	}
+}
@@ -4,7 +4,7 @@ go 1.13
 
 require (
 	github.com/aws/aws-sdk-go v1.28.1
-	github.com/bwmarrin/discordgo v0.20.2
+	github.com/bwmarrin/discordgo v0.27.1
 	github.com/colinfike/mimic v1.0.1
 	github.com/rs/zerolog v1.17.2 // indirect
 	github.com/rylio/ytdl v0.6.2
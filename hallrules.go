@@ -0,0 +1,375 @@
+package judgego
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	hallRulesFilename = "hallRules.json"
+	// defaultInductionMinCount is the threshold for the built in fame and shame halls
+	defaultInductionMinCount = 3
+	// countAuthorFlag lets the message author's own reaction count towards induction
+	countAuthorFlag = "--count-author"
+	// reversalFlag sets what happens to the hall post when the original is deleted or loses its votes
+	reversalFlag = "--reversal"
+
+	// reversalKeep leaves the hall post alone, reversalEdit marks it as revoked and
+	// reversalRemove deletes it so the message can be voted in again
+	reversalKeep   = "keep"
+	reversalEdit   = "edit"
+	reversalRemove = "remove"
+
+	revokedDeletedNote = "**Revoked: the original message was deleted.**"
+	revokedVotesNote   = "**Revoked: the original message no longer has enough votes.**"
+
+	fameTemplate  = "**Posted on {date} by {author}.**\n**Voted in by {voters}**"
+	shameTemplate = "**Posted in infamy on {date} by {author}.**\n**Voted in by {voters}**"
+
+	// maxEmbedDescription is the most text Discord allows in an embed description
+	maxEmbedDescription = 2048
+	// maxEmbedFieldValue is the most text Discord allows in an embed field
+	maxEmbedFieldValue = 1024
+	// messageLinkFormat is a jump link to a message, filled with guild, channel and message IDs
+	messageLinkFormat = "https://discord.com/channels/%v/%v/%v"
+)
+
+// imageExtensions are the attachment types Discord will render inline in an embed
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp"}
+
+// inductionRule describes a hall: the emoji that votes a message in, how many votes it takes,
+// where the message gets reposted and what the repost looks like.
+type inductionRule struct {
+	Name        string `json:"name"`
+	Emoji       string `json:"emoji"`
+	Threshold   int    `json:"threshold"`
+	ChannelID   string `json:"channelId"`
+	Template    string `json:"template"`
+	CountAuthor bool   `json:"countAuthor"`
+	Reversal    string `json:"reversal,omitempty"`
+}
+
+// hallRuleSet holds the induction rules of every guild that has customized them. Guilds
+// without an entry use defaultHallRules.
+type hallRuleSet struct {
+	sync.RWMutex
+	m map[string][]inductionRule
+}
+
+var hallRules = hallRuleSet{m: make(map[string][]inductionRule)}
+
+// defaultHallRules are the original hardcoded halls of fame and shame, configured through env variables.
+func defaultHallRules() []inductionRule {
+	rules := make([]inductionRule, 0)
+	if id := os.Getenv("HALL_OF_FAME_ID"); id != "" {
+		rules = append(rules, inductionRule{Name: "fame", Emoji: "👌", Threshold: defaultInductionMinCount, ChannelID: id, Template: fameTemplate})
+	}
+	if id := os.Getenv("HALL_OF_SHAME_ID"); id != "" {
+		rules = append(rules, inductionRule{Name: "shame", Emoji: "💩", Threshold: defaultInductionMinCount, ChannelID: id, Template: shameTemplate})
+	}
+	return rules
+}
+
+func getHallRules(guildID string) []inductionRule {
+	hallRules.RLock()
+	defer hallRules.RUnlock()
+	rules, ok := hallRules.m[guildID]
+	if !ok {
+		return defaultHallRules()
+	}
+	return append([]inductionRule{}, rules...)
+}
+
+// loadHallRules reads the rules out of the hall store, falling back to legacy if they haven't
+// been saved there yet.
+func loadHallRules(legacy SoundStore) error {
+	b, err := hallStore.Get(hallRulesFilename)
+	if err == errNotFound && legacy != nil {
+		b, err = legacy.Get(hallRulesFilename)
+	}
+	if err == errNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[string][]inductionRule)
+	err = json.Unmarshal(b, &rules)
+	if err != nil {
+		return err
+	}
+
+	hallRules.Lock()
+	hallRules.m = rules
+	hallRules.Unlock()
+	return nil
+}
+
+// saveHallRules persists the rules. Callers must hold the lock.
+func saveHallRules() error {
+	b, err := json.Marshal(hallRules.m)
+	if err != nil {
+		return err
+	}
+	return hallStore.Put(hallRulesFilename, b)
+}
+
+// updateHallRules applies update to the guild's rules and saves the result.
+func updateHallRules(guildID string, update func([]inductionRule) ([]inductionRule, error)) error {
+	hallRules.Lock()
+	defer hallRules.Unlock()
+	rules, ok := hallRules.m[guildID]
+	if !ok {
+		rules = defaultHallRules()
+	}
+	rules, err := update(rules)
+	if err != nil {
+		return err
+	}
+	hallRules.m[guildID] = rules
+
+	err = saveHallRules()
+	if err != nil {
+		return retryableError(codeStorage, "Error saving hall rules", err)
+	}
+	return nil
+}
+
+func findHallRule(rules []inductionRule, name string) int {
+	for i, rule := range rules {
+		if strings.EqualFold(rule.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findReaction returns the reaction on the message matching the emoji's API name, or nil.
+func findReaction(m *discordgo.Message, emoji string) *discordgo.MessageReactions {
+	for _, reaction := range m.Reactions {
+		if reaction.Emoji.APIName() == emoji {
+			return reaction
+		}
+	}
+	return nil
+}
+
+// filterVoters drops the reactions that shouldn't count towards induction: bots, the author
+// (unless the rule allows it) and anyone isNewMember flags.
+func filterVoters(users []*discordgo.User, authorID string, rule inductionRule, isNewMember func(userID string) bool) []*discordgo.User {
+	voters := make([]*discordgo.User, 0, len(users))
+	for _, user := range users {
+		if user.Bot {
+			continue
+		}
+		if user.ID == authorID && !rule.CountAuthor {
+			continue
+		}
+		if isNewMember(user.ID) {
+			continue
+		}
+		voters = append(voters, user)
+	}
+	return voters
+}
+
+// revokedContent is the hall post's content with the revocation note put in front of it.
+func revokedContent(content, note string) string {
+	if strings.HasPrefix(content, note) {
+		return content
+	}
+	return note + "\n" + content
+}
+
+func usernames(users []*discordgo.User) []string {
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+// formatInduction fills in the rule's template for the inducted message. The embed carries the
+// message itself, so {content} left over in templates saved before that is dropped.
+func formatInduction(rule inductionRule, m *discordgo.Message, voters []string) string {
+	template := rule.Template
+	if template == "" {
+		template = fameTemplate
+	}
+	r := strings.NewReplacer(
+		"{date}", m.Timestamp.Format("January 2, 2006"),
+		"{author}", m.Author.Username,
+		"{voters}", strings.Join(voters, ", "),
+		"{content}", "",
+		"{hall}", rule.Name,
+	)
+	return strings.TrimSpace(r.Replace(template))
+}
+
+// buildInductionEmbed renders the inducted message as an embed with the author, a jump link
+// back to the original, the first image inline and any other attachments listed.
+func buildInductionEmbed(m *discordgo.Message, guildID, channelName string) *discordgo.MessageEmbed {
+	link := jumpLink(guildID, m)
+	jump := "\n\n[Jump to message](" + link + ")"
+	content := m.Content
+	if runes := []rune(content); len(runes)+len(jump) > maxEmbedDescription {
+		content = string(runes[:maxEmbedDescription-len(jump)-3]) + "..."
+	}
+
+	embed := &discordgo.MessageEmbed{
+		URL:         link,
+		Description: content + jump,
+		Timestamp:   m.Timestamp.Format(time.RFC3339),
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    m.Author.Username,
+			IconURL: m.Author.AvatarURL(""),
+		},
+	}
+	if channelName != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "#" + channelName}
+	}
+
+	others := make([]string, 0)
+	for _, att := range m.Attachments {
+		if embed.Image == nil && isImageAttachment(att) {
+			embed.Image = &discordgo.MessageEmbedImage{URL: att.URL}
+			continue
+		}
+		others = append(others, "["+att.Filename+"]("+att.URL+")")
+	}
+	// Links to images show up as embeds on the original, use the first one if nothing was attached
+	for _, original := range m.Embeds {
+		if embed.Image != nil {
+			break
+		}
+		if original.Image != nil {
+			embed.Image = &discordgo.MessageEmbedImage{URL: original.Image.URL}
+		} else if original.Thumbnail != nil {
+			embed.Image = &discordgo.MessageEmbedImage{URL: original.Thumbnail.URL}
+		}
+	}
+	if len(others) > 0 {
+		embed.Fields = []*discordgo.MessageEmbedField{{Name: "Attachments", Value: attachmentList(others)}}
+	}
+	return embed
+}
+
+// attachmentList puts the attachment links on separate lines, as many as fit in an embed field.
+func attachmentList(links []string) string {
+	value := ""
+	for i, link := range links {
+		if i > 0 {
+			link = "\n" + link
+		}
+		// Leave room to say how many were left out
+		rest := ""
+		if i < len(links)-1 {
+			rest = fmt.Sprintf("\n...and %v more", len(links)-i-1)
+		}
+		if len(value)+len(link)+len(rest) > maxEmbedFieldValue {
+			return strings.TrimPrefix(value+fmt.Sprintf("\n...and %v more", len(links)-i), "\n")
+		}
+		value += link
+	}
+	return value
+}
+
+func jumpLink(guildID string, m *discordgo.Message) string {
+	return fmt.Sprintf(messageLinkFormat, guildID, m.ChannelID, m.ID)
+}
+
+func isImageAttachment(att *discordgo.MessageAttachment) bool {
+	if att.Width > 0 && att.Height > 0 {
+		return true
+	}
+	name := strings.ToLower(att.Filename)
+	for _, ext := range imageExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveHallCommand(hallCmd hallCommand, guildID string) (string, error) {
+	switch hallCmd.action {
+	case "add":
+		err := updateHallRules(guildID, func(rules []inductionRule) ([]inductionRule, error) {
+			if i := findHallRule(rules, hallCmd.rule.Name); i >= 0 {
+				rules[i] = hallCmd.rule
+				return rules, nil
+			}
+			return append(rules, hallCmd.rule), nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Hall " + hallCmd.rule.Name + " saved!", nil
+	case "remove":
+		err := updateHallRules(guildID, func(rules []inductionRule) ([]inductionRule, error) {
+			i := findHallRule(rules, hallCmd.rule.Name)
+			if i < 0 {
+				return nil, newUserError(codeNotFound, "No hall named "+hallCmd.rule.Name)
+			}
+			return append(rules[:i], rules[i+1:]...), nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Hall " + hallCmd.rule.Name + " removed!", nil
+	default:
+		return listHallRules(getHallRules(guildID)), nil
+	}
+}
+
+func listHallRules(rules []inductionRule) string {
+	if len(rules) == 0 {
+		return "No halls are set up."
+	}
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		line := fmt.Sprintf("**%v** - %v x%v → <#%v>", rule.Name, formatEmoji(rule.Emoji), rule.Threshold, rule.ChannelID)
+		if rule.CountAuthor {
+			line += " (author's vote counts)"
+		}
+		if rule.Reversal != "" && rule.Reversal != reversalKeep {
+			line += " (" + rule.Reversal + " on reversal)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseEmoji turns a unicode emoji or a custom emoji like <:name:id> into its API name.
+func parseEmoji(token string) string {
+	if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+		token = strings.TrimPrefix(strings.TrimSuffix(token, ">"), "<")
+		token = strings.TrimPrefix(strings.TrimPrefix(token, "a"), ":")
+	}
+	return token
+}
+
+// formatEmoji turns an emoji API name back into something that renders in a message.
+func formatEmoji(apiName string) string {
+	if strings.Contains(apiName, ":") {
+		return "<:" + apiName + ">"
+	}
+	return apiName
+}
+
+// parseChannelMention accepts a channel mention like <#id> or a bare channel ID.
+func parseChannelMention(token string) (string, bool) {
+	id := strings.TrimSuffix(strings.TrimPrefix(token, "<#"), ">")
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return "", false
+	}
+	return id, true
+}
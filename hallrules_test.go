@@ -0,0 +1,123 @@
+package judgego
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHallCmd(t *testing.T) {
+	hallCmd, err := parseHallCmd(`$hall add wow <:pog:1234> 5 <#5678> --count-author **{author}** did it\nvoted by {voters}`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "add", hallCmd.action)
+	assert.Equal(t, inductionRule{
+		Name:        "wow",
+		Emoji:       "pog:1234",
+		Threshold:   5,
+		ChannelID:   "5678",
+		Template:    "**{author}** did it\nvoted by {voters}",
+		CountAuthor: true,
+	}, hallCmd.rule)
+
+	hallCmd, err = parseHallCmd("$hall")
+	assert.Nil(t, err)
+	assert.Equal(t, "list", hallCmd.action)
+
+	hallCmd, err = parseHallCmd("$hall add wow 👌 3 <#5678> --reversal edit --count-author")
+	assert.Nil(t, err)
+	assert.Equal(t, reversalEdit, hallCmd.rule.Reversal)
+	assert.True(t, hallCmd.rule.CountAuthor)
+	assert.Equal(t, fameTemplate, hallCmd.rule.Template)
+
+	for _, msg := range []string{"$hall add wow 👌 zero <#5678>", "$hall add wow 👌 3 general", "$hall remove", "$hall rename wow", "$hall add wow 👌 3 <#5678> --reversal", "$hall add wow 👌 3 <#5678> --reversal undo"} {
+		_, err = parseHallCmd(msg)
+		assert.NotNil(t, err, msg)
+	}
+}
+
+func TestParseEmoji(t *testing.T) {
+	assert.Equal(t, "👌", parseEmoji("👌"))
+	assert.Equal(t, "pog:1234", parseEmoji("<:pog:1234>"))
+	assert.Equal(t, "angry:1234", parseEmoji("<a:angry:1234>"))
+	assert.Equal(t, "<:pog:1234>", formatEmoji("pog:1234"))
+}
+
+func TestFormatInduction(t *testing.T) {
+	m := &discordgo.Message{
+		Content:   "big if true",
+		Timestamp: time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC),
+		Author:    &discordgo.User{Username: "colin"},
+	}
+
+	txt := formatInduction(inductionRule{Template: shameTemplate}, m, []string{"a", "b"})
+
+	assert.Equal(t, "**Posted in infamy on January 2, 2020 by colin.**\n**Voted in by a, b**", txt)
+
+	// Templates saved before inductions had embeds don't repeat the message
+	oldTemplate := shameTemplate + "\n\n{content}"
+	txt = formatInduction(inductionRule{Template: oldTemplate}, m, []string{"a", "b"})
+	assert.Equal(t, "**Posted in infamy on January 2, 2020 by colin.**\n**Voted in by a, b**", txt)
+}
+
+func TestBuildInductionEmbed(t *testing.T) {
+	m := &discordgo.Message{
+		ID:        "3",
+		ChannelID: "2",
+		Content:   "look at this",
+		Timestamp: time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC),
+		Author:    &discordgo.User{ID: "1", Username: "colin"},
+		Attachments: []*discordgo.MessageAttachment{
+			{Filename: "notes.txt", URL: "https://cdn/notes.txt"},
+			{Filename: "cat.PNG", URL: "https://cdn/cat.PNG"},
+			{Filename: "dog.jpg", URL: "https://cdn/dog.jpg"},
+		},
+	}
+
+	embed := buildInductionEmbed(m, "1", "general")
+
+	assert.Equal(t, "https://discord.com/channels/1/2/3", embed.URL)
+	assert.Equal(t, "look at this\n\n[Jump to message](https://discord.com/channels/1/2/3)", embed.Description)
+	assert.Equal(t, "colin", embed.Author.Name)
+	assert.Equal(t, "#general", embed.Footer.Text)
+	assert.Equal(t, "https://cdn/cat.PNG", embed.Image.URL)
+	assert.Equal(t, "[notes.txt](https://cdn/notes.txt)\n[dog.jpg](https://cdn/dog.jpg)", embed.Fields[0].Value)
+}
+
+func TestAttachmentList(t *testing.T) {
+	assert.Equal(t, "a\nb", attachmentList([]string{"a", "b"}))
+
+	links := make([]string, 0)
+	for i := 0; i < 30; i++ {
+		links = append(links, fmt.Sprintf("[file%02d.txt](https://cdn.discordapp.com/attachments/1/2/file%02d.txt)", i, i))
+	}
+	value := attachmentList(links)
+	assert.True(t, len(value) <= maxEmbedFieldValue)
+	assert.True(t, strings.HasSuffix(value, "more"))
+}
+
+func TestFilterVoters(t *testing.T) {
+	users := []*discordgo.User{
+		{ID: "author", Username: "author"},
+		{ID: "bot", Username: "bot", Bot: true},
+		{ID: "new", Username: "new"},
+		{ID: "old", Username: "old"},
+	}
+	isNewMember := func(userID string) bool { return userID == "new" }
+
+	voters := filterVoters(users, "author", inductionRule{}, isNewMember)
+	assert.Equal(t, []string{"old"}, usernames(voters))
+
+	voters = filterVoters(users, "author", inductionRule{CountAuthor: true}, isNewMember)
+	assert.Equal(t, []string{"author", "old"}, usernames(voters))
+}
+
+func TestRevokedContent(t *testing.T) {
+	content := revokedContent("**Posted by colin.**", revokedDeletedNote)
+	assert.Equal(t, revokedDeletedNote+"\n**Posted by colin.**", content)
+	assert.Equal(t, content, revokedContent(content, revokedDeletedNote))
+}
@@ -1,59 +1,223 @@
 package judgego
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 )
 
-const reactionHistoryFilename = "reactionHistory.json"
+const (
+	// hallDir is the local directory hall rules and inductions are stored in when S3 persistence is off
+	hallDir = "halls"
+	// hallFilePrefix is the bucket prefix hall rules and inductions are stored under
+	hallFilePrefix = "halls/"
+	// inductionFilePrefix starts the name of every induction record in the hall store
+	inductionFilePrefix = "induction-"
+	// reactionHistoryFilename is the legacy single file every induction used to be written to
+	reactionHistoryFilename = "reactionHistory.json"
+)
+
+// induction is the record of a message getting voted into a hall.
+type induction struct {
+	Hall       string    `json:"hall"`
+	GuildID    string    `json:"guildId"`
+	ChannelID  string    `json:"channelId"`
+	MessageID  string    `json:"messageId"`
+	AuthorID   string    `json:"authorId"`
+	AuthorName string    `json:"authorName"`
+	Reactors   []string  `json:"reactors"`
+	InductedAt time.Time `json:"inductedAt"`
+	// HallChannelID and HallMessageID point at the post in the hall, they're empty for old records
+	HallChannelID string `json:"hallChannelId,omitempty"`
+	HallMessageID string `json:"hallMessageId,omitempty"`
+	// Revoked is set once the original was deleted or lost its votes under the edit reversal policy
+	Revoked bool `json:"revoked,omitempty"`
+}
 
 type inductionMap struct {
 	sync.RWMutex
-	m map[string]bool
+	m map[string]induction
+}
+
+var reactionHistory = inductionMap{m: make(map[string]induction)}
+
+// hallStore is where hall rules and induction records are persisted. It's selected once in Start.
+var hallStore SoundStore
+
+// newHallStore returns the store for hall data selected by the S3_PERSISTENCE env variable.
+func newHallStore() (SoundStore, error) {
+	if s3Persistence == "true" {
+		return newS3Store(bucketName, hallFilePrefix)
+	}
+	return newLocalStore(hallDir)
+}
+
+// newLegacyHallStore returns the root of the bucket, where hall data was kept before it had its
+// own store. Hall data always went to the bucket, whatever S3_PERSISTENCE says, so it's only nil
+// when there's no bucket to migrate from.
+func newLegacyHallStore() (SoundStore, error) {
+	if bucketName != "" {
+		return newS3Store(bucketName, "")
+	}
+	return nil, nil
 }
 
-var reactionHistory = loadReactionHistory()
+func inductionKey(channelID, messageID string) string {
+	return channelID + messageID
+}
 
 func alreadyInducted(channelID, messageID string) bool {
 	reactionHistory.RLock()
 	defer reactionHistory.RUnlock()
-	if _, ok := reactionHistory.m[channelID+messageID]; ok {
+	if _, ok := reactionHistory.m[inductionKey(channelID, messageID)]; ok {
 		return true
 	}
 	return false
 }
 
-func inductMessage(channelID, messageID string) {
+// getInduction returns the induction record of the message, if it was inducted.
+func getInduction(channelID, messageID string) (induction, bool) {
+	reactionHistory.RLock()
+	defer reactionHistory.RUnlock()
+	record, ok := reactionHistory.m[inductionKey(channelID, messageID)]
+	return record, ok
+}
+
+// inductMessage records the induction and persists it as its own file, so nothing else
+// has to be rewritten. The record is kept in memory even if saving fails so the message
+// doesn't get inducted twice while the bot is running.
+func inductMessage(record induction) error {
+	key := inductionKey(record.ChannelID, record.MessageID)
 	reactionHistory.Lock()
-	defer reactionHistory.Unlock()
-	reactionHistory.m[channelID+messageID] = true
-	saveReactionHistory()
+	reactionHistory.m[key] = record
+	reactionHistory.Unlock()
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return hallStore.Put(inductionFilename(key), b)
+}
+
+// removeInduction forgets the induction entirely so the message can be inducted again.
+func removeInduction(record induction) error {
+	key := inductionKey(record.ChannelID, record.MessageID)
+	reactionHistory.Lock()
+	delete(reactionHistory.m, key)
+	reactionHistory.Unlock()
+
+	err := hallStore.Delete(inductionFilename(key))
+	if err == errNotFound {
+		return nil
+	}
+	return err
+}
+
+func inductionFilename(key string) string {
+	return inductionFilePrefix + key + metadataExt
+}
+
+// inductions returns a copy of every induction record.
+func inductions() []induction {
+	reactionHistory.RLock()
+	defer reactionHistory.RUnlock()
+	records := make([]induction, 0, len(reactionHistory.m))
+	for _, record := range reactionHistory.m {
+		records = append(records, record)
+	}
+	return records
 }
 
-func loadReactionHistory() inductionMap {
-	reactionMap := make(map[string]bool)
-	// TODO: Just assuming a failure here means the file doesn't exist in s3 for now. Should handle situation where it actually fails.
-	b, err := getFromS3(reactionHistoryFilename)
+// loadReactionHistory reads every induction record out of the hall store. If there are none
+// yet, the legacy reaction history is migrated from legacy. Unlike a missing file, a failed read
+// is returned rather than starting over, which would let old messages get inducted again.
+func loadReactionHistory(legacy SoundStore) error {
+	names, err := hallStore.List()
 	if err != nil {
-		return inductionMap{m: reactionMap}
+		return err
+	}
+
+	reactionMap := make(map[string]induction)
+	for _, name := range names {
+		if !strings.HasPrefix(name, inductionFilePrefix) || !strings.HasSuffix(name, metadataExt) {
+			continue
+		}
+		b, err := hallStore.Get(name)
+		if err != nil {
+			return err
+		}
+		var record induction
+		err = json.Unmarshal(b, &record)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+		reactionMap[strings.TrimSuffix(strings.TrimPrefix(name, inductionFilePrefix), metadataExt)] = record
+	}
+
+	if len(reactionMap) == 0 && legacy != nil {
+		reactionMap, err = migrateReactionHistory(legacy)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = json.Unmarshal(b, &reactionMap)
+	reactionHistory.Lock()
+	reactionHistory.m = reactionMap
+	reactionHistory.Unlock()
+	return nil
+}
+
+// migrateReactionHistory copies every induction in the legacy reaction history into the hall
+// store. The legacy file is left alone.
+func migrateReactionHistory(legacy SoundStore) (map[string]induction, error) {
+	b, err := legacy.Get(reactionHistoryFilename)
+	if err == errNotFound {
+		return make(map[string]induction), nil
+	}
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return inductionMap{m: reactionMap}
+	reactionMap, err := decodeReactionHistory(b)
+	if err != nil {
+		return nil, err
+	}
+	for key, record := range reactionMap {
+		b, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		err = hallStore.Put(inductionFilename(key), b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("Migrated %v inductions from %v", len(reactionMap), reactionHistoryFilename)
+	return reactionMap, nil
 }
 
-func saveReactionHistory() error {
-	b, err := json.Marshal(reactionHistory.m)
+// decodeReactionHistory reads the reaction history, including the old format where every
+// inducted message just mapped to true.
+func decodeReactionHistory(b []byte) (map[string]induction, error) {
+	raw := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &raw)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	buf := bytes.NewBuffer(b)
-	writeToS3(buf, reactionHistoryFilename)
-	return nil
+
+	reactionMap := make(map[string]induction, len(raw))
+	for key, value := range raw {
+		var record induction
+		if string(value) != "true" {
+			err = json.Unmarshal(value, &record)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reactionMap[key] = record
+	}
+	return reactionMap, nil
 }
@@ -0,0 +1,102 @@
+package judgego
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useMemoryHallStore swaps the hall store for an empty memoryStore and clears the inductions.
+func useMemoryHallStore() (*memoryStore, func()) {
+	store := newMemoryStore()
+	oldStore := hallStore
+	hallStore = store
+	reactionHistory.Lock()
+	oldHistory := reactionHistory.m
+	reactionHistory.m = make(map[string]induction)
+	reactionHistory.Unlock()
+	return store, func() {
+		hallStore = oldStore
+		reactionHistory.Lock()
+		reactionHistory.m = oldHistory
+		reactionHistory.Unlock()
+	}
+}
+
+func TestInductMessagePersists(t *testing.T) {
+	store, restore := useMemoryHallStore()
+	defer restore()
+
+	record := induction{Hall: "fame", GuildID: "g", ChannelID: "12", MessageID: "34", AuthorID: "1"}
+	assert.Nil(t, inductMessage(record))
+	assert.True(t, alreadyInducted("12", "34"))
+
+	exists, err := store.Exists("induction-1234.json")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	// A fresh load only sees what was written to the store
+	reactionHistory.m = make(map[string]induction)
+	assert.False(t, alreadyInducted("12", "34"))
+	assert.Nil(t, loadReactionHistory(nil))
+	assert.True(t, alreadyInducted("12", "34"))
+	assert.Equal(t, []induction{record}, inductions())
+}
+
+func TestRemoveInduction(t *testing.T) {
+	store, restore := useMemoryHallStore()
+	defer restore()
+
+	record := induction{Hall: "fame", ChannelID: "12", MessageID: "34", HallChannelID: "56", HallMessageID: "78"}
+	assert.Nil(t, inductMessage(record))
+	got, ok := getInduction("12", "34")
+	assert.True(t, ok)
+	assert.Equal(t, record, got)
+
+	assert.Nil(t, removeInduction(record))
+	assert.False(t, alreadyInducted("12", "34"))
+	names, _ := store.List()
+	assert.Empty(t, names)
+	// Removing twice is fine
+	assert.Nil(t, removeInduction(record))
+}
+
+func TestLoadReactionHistoryMigratesLegacy(t *testing.T) {
+	store, restore := useMemoryHallStore()
+	defer restore()
+
+	legacy := newMemoryStore()
+	legacy.Put(reactionHistoryFilename, []byte(`{"1234":true,"5678":{"hall":"shame","channelId":"56","messageId":"78"}}`))
+
+	assert.Nil(t, loadReactionHistory(legacy))
+	assert.True(t, alreadyInducted("12", "34"))
+	assert.True(t, alreadyInducted("56", "78"))
+	names, _ := store.List()
+	assert.ElementsMatch(t, []string{"induction-1234.json", "induction-5678.json"}, names)
+
+	// Once migrated the legacy file is ignored
+	legacy.Put(reactionHistoryFilename, []byte(`{"9999":true}`))
+	assert.Nil(t, loadReactionHistory(legacy))
+	assert.False(t, alreadyInducted("99", "99"))
+}
+
+func TestLoadReactionHistoryFailure(t *testing.T) {
+	_, restore := useMemoryHallStore()
+	defer restore()
+	assert.Nil(t, inductMessage(induction{ChannelID: "12", MessageID: "34"}))
+
+	hallStore = failingStore{newMemoryStore()}
+	assert.NotNil(t, loadReactionHistory(nil))
+	// The existing history is kept rather than replaced by an empty one
+	assert.True(t, alreadyInducted("12", "34"))
+}
+
+// failingStore is a SoundStore that can't list anything.
+type failingStore struct {
+	*memoryStore
+}
+
+func (failingStore) List() ([]string, error) {
+	return nil, errors.New("connection reset")
+}
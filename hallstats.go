@@ -0,0 +1,180 @@
+package judgego
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// leaderboardSize is how many people are shown per hall in a leaderboard
+	leaderboardSize = 10
+	day             = 24 * time.Hour
+)
+
+// namedPeriods are the periods that can be passed to $halls by name. All time is a zero period.
+var namedPeriods = map[string]time.Duration{
+	"week":  7 * day,
+	"month": 30 * day,
+	"year":  365 * day,
+	"all":   0,
+}
+
+// hallCount is how many times someone shows up in a hall.
+type hallCount struct {
+	userID string
+	name   string
+	count  int
+}
+
+// parsePeriod accepts a named period or a number of days like 14d.
+func parsePeriod(token string) (time.Duration, bool) {
+	token = strings.ToLower(token)
+	if period, ok := namedPeriods[token]; ok {
+		return period, true
+	}
+	if strings.HasSuffix(token, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(token, "d"))
+		if err == nil && days > 0 {
+			return time.Duration(days) * day, true
+		}
+	}
+	return 0, false
+}
+
+func describePeriod(period time.Duration) string {
+	if period == 0 {
+		return "all time"
+	}
+	return fmt.Sprintf("past %v days", int(period/day))
+}
+
+// guildInductions returns the guild's standing inductions made within period of now. A zero period means all of them.
+func guildInductions(records []induction, guildID string, period time.Duration, now time.Time) []induction {
+	filtered := make([]induction, 0)
+	for _, record := range records {
+		if record.GuildID != guildID || record.Revoked {
+			continue
+		}
+		if period > 0 && now.Sub(record.InductedAt) > period {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+	return filtered
+}
+
+// leaderboards counts inductions per author for every hall, sorted most inducted first.
+func leaderboards(records []induction) map[string][]hallCount {
+	counts := make(map[string]map[string]*hallCount)
+	for _, record := range records {
+		if counts[record.Hall] == nil {
+			counts[record.Hall] = make(map[string]*hallCount)
+		}
+		c, ok := counts[record.Hall][record.AuthorID]
+		if !ok {
+			c = &hallCount{userID: record.AuthorID, name: record.AuthorName}
+			counts[record.Hall][record.AuthorID] = c
+		}
+		c.count++
+	}
+
+	boards := make(map[string][]hallCount)
+	for hall, byAuthor := range counts {
+		board := make([]hallCount, 0, len(byAuthor))
+		for _, c := range byAuthor {
+			board = append(board, *c)
+		}
+		sort.Slice(board, func(i, j int) bool {
+			if board[i].count != board[j].count {
+				return board[i].count > board[j].count
+			}
+			return board[i].name < board[j].name
+		})
+		boards[hall] = board
+	}
+	return boards
+}
+
+func formatLeaderboards(boards map[string][]hallCount, hall string, period time.Duration) string {
+	halls := make([]string, 0, len(boards))
+	for name := range boards {
+		if hall == "" || strings.EqualFold(name, hall) {
+			halls = append(halls, name)
+		}
+	}
+	if len(halls) == 0 {
+		return "Nobody has been inducted (" + describePeriod(period) + ")."
+	}
+	sort.Strings(halls)
+
+	var sb strings.Builder
+	for _, name := range halls {
+		sb.WriteString(fmt.Sprintf("**Hall of %v (%v)**\n", name, describePeriod(period)))
+		for i, c := range boards[name] {
+			if i == leaderboardSize {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("%v. %v - %v\n", i+1, c.name, c.count))
+		}
+	}
+	return sb.String()
+}
+
+// userHallStats sums up how often the user was inducted into and voted for each hall.
+func userHallStats(records []induction, userID string, period time.Duration) string {
+	inducted := make(map[string]int)
+	voted := make(map[string]int)
+	halls := make([]string, 0)
+	name := ""
+	track := func(hall string) {
+		if _, ok := inducted[hall]; !ok {
+			inducted[hall] = 0
+			voted[hall] = 0
+			halls = append(halls, hall)
+		}
+	}
+
+	for _, record := range records {
+		if record.AuthorID == userID {
+			track(record.Hall)
+			inducted[record.Hall]++
+			name = record.AuthorName
+		}
+		for _, reactor := range record.Reactors {
+			if reactor == userID {
+				track(record.Hall)
+				voted[record.Hall]++
+			}
+		}
+	}
+	if name == "" {
+		name = "<@" + userID + ">"
+	}
+	if len(halls) == 0 {
+		return name + " hasn't been in any halls (" + describePeriod(period) + ")."
+	}
+	sort.Strings(halls)
+
+	lines := []string{fmt.Sprintf("**%v (%v)**", name, describePeriod(period))}
+	for _, hall := range halls {
+		lines = append(lines, fmt.Sprintf("%v: %v inducted, %v votes cast", hall, inducted[hall], voted[hall]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func resolveHallsCommand(hallsCmd hallsCommand, guildID, authorID string) string {
+	records := guildInductions(inductions(), guildID, hallsCmd.period, time.Now())
+	switch hallsCmd.action {
+	case "user":
+		userID := hallsCmd.userID
+		if hallsCmd.self {
+			userID = authorID
+		}
+		return userHallStats(records, userID, hallsCmd.period)
+	default:
+		return formatLeaderboards(leaderboards(records), hallsCmd.hall, hallsCmd.period)
+	}
+}
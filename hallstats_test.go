@@ -0,0 +1,94 @@
+package judgego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHallsCmd(t *testing.T) {
+	hallsCmd, err := parseHallsCmd("$halls")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "top"}, hallsCmd)
+
+	hallsCmd, err = parseHallsCmd("$halls top shame week")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "top", hall: "shame", period: 7 * day}, hallsCmd)
+
+	hallsCmd, err = parseHallsCmd("$halls month")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "top", period: 30 * day}, hallsCmd)
+
+	hallsCmd, err = parseHallsCmd("$halls me 14d")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "user", self: true, period: 14 * day}, hallsCmd)
+
+	hallsCmd, err = parseHallsCmd("$halls <@!1234>")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "user", userID: "1234"}, hallsCmd)
+
+	for _, msg := range []string{"$halls fortnight", "$halls me 0d", "$halls top fame week extra"} {
+		_, err = parseHallsCmd(msg)
+		assert.NotNil(t, err, msg)
+	}
+}
+
+func TestParseHallsBackfillCmd(t *testing.T) {
+	hallsCmd, err := parseHallsCmd("$halls backfill <#5678>")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "backfill", channelID: "5678"}, hallsCmd)
+
+	hallsCmd, err = parseHallsCmd("$halls backfill <#5678> since 2020-03-01")
+	assert.Nil(t, err)
+	assert.Equal(t, hallsCommand{action: "backfill", channelID: "5678", since: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}, hallsCmd)
+
+	hallsCmd, err = parseHallsCmd("$halls backfill 5678 2020-03-01")
+	assert.Nil(t, err)
+	assert.Equal(t, "5678", hallsCmd.channelID)
+
+	for _, msg := range []string{"$halls backfill", "$halls backfill general", "$halls backfill <#5678> since", "$halls backfill <#5678> since March", "$halls backfill <#5678> since 2020-03-01 now"} {
+		_, err = parseHallsCmd(msg)
+		assert.NotNil(t, err, msg)
+	}
+}
+
+func TestLeaderboards(t *testing.T) {
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	records := []induction{
+		{Hall: "fame", GuildID: "g", AuthorID: "1", AuthorName: "colin", Reactors: []string{"2", "3"}, InductedAt: now.Add(-time.Hour)},
+		{Hall: "fame", GuildID: "g", AuthorID: "1", AuthorName: "colin", Reactors: []string{"2"}, InductedAt: now.Add(-40 * day)},
+		{Hall: "fame", GuildID: "g", AuthorID: "2", AuthorName: "bert", Reactors: []string{"1"}, InductedAt: now.Add(-2 * day)},
+		{Hall: "shame", GuildID: "g", AuthorID: "2", AuthorName: "bert", Reactors: []string{"1", "3"}, InductedAt: now.Add(-3 * day)},
+		{Hall: "fame", GuildID: "other", AuthorID: "3", AuthorName: "ernie", InductedAt: now},
+		{Hall: "fame", GuildID: "g", AuthorID: "3", AuthorName: "ernie", InductedAt: now, Revoked: true},
+		// Legacy records from before inductions were tracked have no details
+		{},
+	}
+
+	all := guildInductions(records, "g", 0, now)
+	assert.Len(t, all, 4)
+	boards := leaderboards(all)
+	assert.Equal(t, []hallCount{{"1", "colin", 2}, {"2", "bert", 1}}, boards["fame"])
+	assert.Equal(t, []hallCount{{"2", "bert", 1}}, boards["shame"])
+
+	month := guildInductions(records, "g", 30*day, now)
+	assert.Equal(t, []hallCount{{"2", "bert", 1}, {"1", "colin", 1}}, leaderboards(month)["fame"])
+
+	assert.Equal(t, "**Hall of shame (all time)**\n1. bert - 1\n", formatLeaderboards(boards, "SHAME", 0))
+	assert.Equal(t, "Nobody has been inducted (past 7 days).", formatLeaderboards(boards, "cringe", 7*day))
+
+	assert.Equal(t, "**bert (all time)**\nfame: 1 inducted, 2 votes cast\nshame: 1 inducted, 0 votes cast", userHallStats(all, "2", 0))
+	assert.Equal(t, "**<@3> (all time)**\nfame: 0 inducted, 1 votes cast\nshame: 0 inducted, 1 votes cast", userHallStats(all, "3", 0))
+	assert.Equal(t, "<@4> hasn't been in any halls (past 30 days).", userHallStats(month, "4", 30*day))
+}
+
+func TestDecodeReactionHistory(t *testing.T) {
+	history, err := decodeReactionHistory([]byte(`{"12":true,"34":{"hall":"fame","guildId":"g","authorId":"1"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, induction{}, history["12"])
+	assert.Equal(t, induction{Hall: "fame", GuildID: "g", AuthorID: "1"}, history["34"])
+
+	_, err = decodeReactionHistory([]byte(`{"12":"yes"}`))
+	assert.NotNil(t, err)
+}
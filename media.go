@@ -0,0 +1,239 @@
+package judgego
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rylio/ytdl"
+)
+
+const (
+	// maxDownloadBytes is the most media we'll download for a single rip
+	maxDownloadBytes = 64 << 20
+)
+
+var (
+	errDownloadTooLarge = errors.New("download exceeded maximum size")
+	errStreamDone       = errors.New("ffmpeg finished reading")
+	errPrivateHost      = errors.New("host is a private address")
+)
+
+// privateNetworks are the address ranges links aren't allowed to point into, on top of loopback,
+// link local and unspecified addresses.
+var privateNetworks = parseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+
+// mediaClient downloads direct links. It refuses to connect to private addresses so links can't
+// reach the bot's own network or cloud metadata endpoints, redirects included.
+var mediaClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 30 * time.Second, Control: rejectPrivateAddress}).DialContext,
+	},
+}
+
+// MediaSource downloads the media behind a URL so it can be converted by ffmpeg.
+type MediaSource interface {
+	Fetch(u *url.URL, w io.Writer) error
+}
+
+// mediaSources maps a URL host to the source that knows how to fetch from it. Hosts
+// without an entry fall back to defaultMediaSource.
+var mediaSources = map[string]MediaSource{}
+
+var defaultMediaSource MediaSource = httpSource{}
+
+func init() {
+	registerMediaSource(youtubeSource{}, "youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com", "youtu.be")
+}
+
+func registerMediaSource(source MediaSource, hosts ...string) {
+	for _, host := range hosts {
+		mediaSources[strings.ToLower(host)] = source
+	}
+}
+
+func mediaSourceFor(u *url.URL) MediaSource {
+	if source, ok := mediaSources[strings.ToLower(u.Hostname())]; ok {
+		return source
+	}
+	return defaultMediaSource
+}
+
+// ripMedia streams the media at rawURL straight into ffmpeg and returns the converted clip.
+// The download is capped at maxDownloadBytes and cut off as soon as ffmpeg has what it needs.
+func ripMedia(rawURL, start, duration, audioFilter string) ([][]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, invalidInput("Invalid URL passed")
+	}
+
+	pr, pw := io.Pipe()
+	capped := &cappedWriter{w: pw, remaining: maxDownloadBytes}
+	fetched := make(chan error, 1)
+	go func() {
+		err := mediaSourceFor(u).Fetch(u, capped)
+		pw.CloseWithError(err)
+		fetched <- err
+	}()
+
+	opusFrames, convertErr := convertToOpusFrames(pr, start, duration, audioFilter)
+	// ffmpeg has everything it's going to read, abort whatever is left of the download
+	pr.CloseWithError(errStreamDone)
+	fetchErr := <-fetched
+
+	if capped.exceeded {
+		return nil, newUserError(codeTooLarge, fmt.Sprintf("Media can be at most %vMB", maxDownloadBytes>>20))
+	}
+	// A download that broke off leaves a truncated clip, and explains a failed conversion better
+	// than ffmpeg can. Downloads cut off because ffmpeg stopped reading aren't failures.
+	if fetchErr != nil && !errors.Is(fetchErr, errStreamDone) {
+		return nil, fetchErr
+	}
+	if convertErr != nil {
+		return nil, convertErr
+	}
+	if len(opusFrames) == 0 {
+		return nil, invalidInput("Couldn't find any audio in that time range")
+	}
+	return opusFrames, nil
+}
+
+// cappedWriter fails writes once more than remaining bytes have been written through it.
+type cappedWriter struct {
+	w         io.Writer
+	remaining int64
+	exceeded  bool
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > c.remaining {
+		c.exceeded = true
+		return 0, errDownloadTooLarge
+	}
+	c.remaining -= int64(len(p))
+	return c.w.Write(p)
+}
+
+// youtubeSource fetches videos through ytdl, preferring audio only streams.
+type youtubeSource struct{}
+
+func (youtubeSource) Fetch(u *url.URL, w io.Writer) error {
+	vid, err := ytdl.GetVideoInfoFromURL(u)
+	if err != nil {
+		return wrapError(codeMedia, "Failed to get video info. Is the url valid?", err)
+	}
+
+	format := selectFormat(vid.Formats)
+	if format == nil {
+		return newUserError(codeMedia, "Couldn't find a video format with audio")
+	}
+	err = vid.Download(format, w)
+	if err != nil {
+		return retryableError(codeMedia, "Error downloading video", err)
+	}
+	return nil
+}
+
+// selectFormat picks the format that gets us audio for the least amount of data. Audio only
+// streams win, lowest bitrate first, otherwise the lowest resolution video that still has audio.
+func selectFormat(formats ytdl.FormatList) *ytdl.Format {
+	var audioOnly, withAudio ytdl.FormatList
+	for _, format := range formats {
+		if format.AudioEncoding == "" {
+			continue
+		}
+		if format.VideoEncoding == "" {
+			audioOnly = append(audioOnly, format)
+		} else {
+			withAudio = append(withAudio, format)
+		}
+	}
+
+	if len(audioOnly) > 0 {
+		return audioOnly.Worst(ytdl.FormatAudioBitrateKey)[0]
+	}
+	if len(withAudio) > 0 {
+		return withAudio.Worst(ytdl.FormatResolutionKey)[0]
+	}
+	return nil
+}
+
+// httpSource fetches direct links to audio or video files.
+type httpSource struct{}
+
+func (httpSource) Fetch(u *url.URL, w io.Writer) error {
+	resp, err := mediaClient.Get(u.String())
+	if errors.Is(err, errPrivateHost) {
+		return invalidInput("Links to private addresses aren't allowed")
+	}
+	if err != nil {
+		return retryableError(codeMedia, "Error downloading media", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newUserError(codeMedia, "Error downloading media: "+resp.Status)
+	}
+	if !isMediaContentType(resp.Header.Get("Content-Type")) {
+		return invalidInput("That link doesn't point at audio or video")
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return retryableError(codeMedia, "Error downloading media", err)
+	}
+	return nil
+}
+
+// rejectPrivateAddress is a dialer Control that fails connections to private addresses. It runs
+// after DNS resolution so a public name resolving to a private address is caught too.
+func rejectPrivateAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateIP(ip) {
+		return errPrivateHost
+	}
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func isMediaContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"audio/", "video/", "application/ogg", "application/octet-stream"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package judgego
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rylio/ytdl"
+	"github.com/stretchr/testify/assert"
+)
+
+// fileSource reads media off the local filesystem so tests don't need the network.
+type fileSource struct{}
+
+func (fileSource) Fetch(u *url.URL, w io.Writer) error {
+	f, err := os.Open(u.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func TestMediaSourceFor(t *testing.T) {
+	registerMediaSource(fileSource{}, "")
+	defer delete(mediaSources, "")
+
+	for rawURL, expected := range map[string]MediaSource{
+		"https://www.youtube.com/watch?v=dFuUCpBbbHw": youtubeSource{},
+		"https://youtu.be/dFuUCpBbbHw":                youtubeSource{},
+		"https://example.com/clip.mp3":                httpSource{},
+		"file:///tmp/clip.mp3":                        fileSource{},
+	} {
+		u, _ := url.Parse(rawURL)
+		assert.Equal(t, expected, mediaSourceFor(u), rawURL)
+	}
+}
+
+func TestFetchFromFileSource(t *testing.T) {
+	registerMediaSource(fileSource{}, "")
+	defer delete(mediaSources, "")
+
+	f, err := ioutil.TempFile("", "judgego")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("media")
+	f.Close()
+
+	u, _ := url.Parse("file://" + f.Name())
+	buf := new(bytes.Buffer)
+	err = mediaSourceFor(u).Fetch(u, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "media", buf.String())
+}
+
+func TestHTTPSourceChecksContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/clip.mp3" {
+			w.Header().Set("Content-Type", "audio/mpeg")
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+	// The test server is on loopback, which the real client refuses
+	oldClient := mediaClient
+	mediaClient = server.Client()
+	defer func() { mediaClient = oldClient }()
+
+	u, _ := url.Parse(server.URL + "/clip.mp3")
+	buf := new(bytes.Buffer)
+	err := httpSource{}.Fetch(u, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "media", buf.String())
+
+	u, _ = url.Parse(server.URL + "/page")
+	err = httpSource{}.Fetch(u, new(bytes.Buffer))
+	assert.NotNil(t, err)
+}
+
+func TestHTTPSourceRejectsPrivateHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("media"))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/clip.mp3")
+	err := httpSource{}.Fetch(u, new(bytes.Buffer))
+	assert.Equal(t, "Links to private addresses aren't allowed", userMessage(err))
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.20.0.1", "192.168.1.1", "169.254.169.254", "::1", "fd00::1"} {
+		assert.True(t, isPrivateIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "172.32.0.1", "2606:4700::1111"} {
+		assert.False(t, isPrivateIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestCappedWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	capped := &cappedWriter{w: buf, remaining: 8}
+
+	_, err := capped.Write([]byte("media"))
+	assert.Nil(t, err)
+	assert.False(t, capped.exceeded)
+
+	_, err = capped.Write([]byte("media"))
+	assert.Equal(t, errDownloadTooLarge, err)
+	assert.True(t, capped.exceeded)
+	assert.Equal(t, "media", buf.String())
+}
+
+func TestSelectFormat(t *testing.T) {
+	video720 := &ytdl.Format{Itag: ytdl.Itag{Resolution: "720p", VideoEncoding: "H.264", AudioEncoding: "aac", AudioBitrate: 192}}
+	video360 := &ytdl.Format{Itag: ytdl.Itag{Resolution: "360p", VideoEncoding: "H.264", AudioEncoding: "aac", AudioBitrate: 96}}
+	videoOnly := &ytdl.Format{Itag: ytdl.Itag{Resolution: "144p", VideoEncoding: "VP9"}}
+	audio128 := &ytdl.Format{Itag: ytdl.Itag{AudioEncoding: "opus", AudioBitrate: 128}}
+	audio64 := &ytdl.Format{Itag: ytdl.Itag{AudioEncoding: "opus", AudioBitrate: 64}}
+
+	assert.Equal(t, audio64, selectFormat(ytdl.FormatList{video720, audio64, videoOnly, audio128}))
+	assert.Equal(t, video360, selectFormat(ytdl.FormatList{video720, videoOnly, video360}))
+	assert.Nil(t, selectFormat(ytdl.FormatList{videoOnly}))
+}
+
+func TestUploadSoundKeepsExisting(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", []byte("frames"))
+
+	err := uploadSound(uploadCommand{name: "mail"}, &discordgo.Message{})
+	assert.Equal(t, "A sound with that name already exists", userMessage(err))
+	data, _ := store.Get("mail")
+	assert.Equal(t, []byte("frames"), data)
+}
@@ -0,0 +1,355 @@
+package judgego
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// metadataDir is the local directory clip metadata is stored in when S3 persistence is off
+	metadataDir = "sounds-meta"
+	// metadataFilePrefix is the bucket prefix clip metadata is stored under
+	metadataFilePrefix = "sound-meta/"
+	// metadataExt is appended to the clip name to get the name of its metadata record
+	metadataExt = ".json"
+	// frameDuration is the length of a single opus frame
+	frameDuration = 20 * time.Millisecond
+)
+
+// clipMetadata is the sidecar record stored alongside every clip.
+type clipMetadata struct {
+	Name           string    `json:"name"`
+	SourceURL      string    `json:"sourceUrl,omitempty"`
+	Start          int       `json:"start"`
+	End            int       `json:"end"`
+	DurationFrames int       `json:"durationFrames"`
+	Effects        string    `json:"effects,omitempty"`
+	CreatedBy      string    `json:"createdBy,omitempty"`
+	CreatedByID    string    `json:"createdById,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	PlayCount      int       `json:"playCount"`
+	Tags           []string  `json:"tags,omitempty"`
+}
+
+// hasTag reports whether the clip is tagged with tag.
+func (meta clipMetadata) hasTag(tag string) bool {
+	return containsString(meta.Tags, tag)
+}
+
+// metadataStore is where clip metadata is persisted. It's selected once in Start alongside soundStore.
+var metadataStore SoundStore
+
+// metadataLock serializes read-modify-write updates of metadata records.
+var metadataLock sync.Mutex
+
+// metadataCache mirrors the metadata store so listing by tag doesn't read every record each
+// time. The bot is the only writer so putMetadata and deleteMetadata keep it current. Once
+// loaded it holds every record. deleted remembers deleted records so a read that was already
+// under way when one was deleted can't bring it back.
+var metadataCache = struct {
+	sync.RWMutex
+	m       map[string]clipMetadata
+	deleted map[string]bool
+	loaded  bool
+}{m: make(map[string]clipMetadata), deleted: make(map[string]bool)}
+
+func resetMetadataCache() {
+	metadataCache.Lock()
+	metadataCache.m = make(map[string]clipMetadata)
+	metadataCache.deleted = make(map[string]bool)
+	metadataCache.loaded = false
+	metadataCache.Unlock()
+}
+
+func newMetadataStore() (SoundStore, error) {
+	if s3Persistence == "true" {
+		return newS3Store(bucketName, metadataFilePrefix)
+	}
+	return newLocalStore(metadataDir)
+}
+
+func getMetadata(name string) (clipMetadata, error) {
+	metadataCache.RLock()
+	meta, ok := metadataCache.m[name]
+	loaded := metadataCache.loaded
+	metadataCache.RUnlock()
+	if ok {
+		return meta, nil
+	}
+	if loaded {
+		return meta, errNotFound
+	}
+
+	meta, err := readMetadata(name + metadataExt)
+	if err != nil {
+		return meta, err
+	}
+	// A putMetadata or deleteMetadata that landed while we were reading is newer than what we read
+	metadataCache.Lock()
+	defer metadataCache.Unlock()
+	if cached, ok := metadataCache.m[name]; ok {
+		return cached, nil
+	}
+	if metadataCache.deleted[name] {
+		return clipMetadata{}, errNotFound
+	}
+	metadataCache.m[name] = meta
+	return meta, nil
+}
+
+func readMetadata(filename string) (clipMetadata, error) {
+	var meta clipMetadata
+	b, err := metadataStore.Get(filename)
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(b, &meta)
+	return meta, err
+}
+
+func putMetadata(meta clipMetadata) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	err = metadataStore.Put(meta.Name+metadataExt, b)
+	if err != nil {
+		return err
+	}
+	metadataCache.Lock()
+	metadataCache.m[meta.Name] = meta
+	delete(metadataCache.deleted, meta.Name)
+	metadataCache.Unlock()
+	return nil
+}
+
+func deleteMetadata(name string) error {
+	metadataCache.Lock()
+	delete(metadataCache.m, name)
+	metadataCache.deleted[name] = true
+	metadataCache.Unlock()
+
+	err := metadataStore.Delete(name + metadataExt)
+	if err == errNotFound {
+		return nil
+	}
+	return err
+}
+
+// allMetadata returns every metadata record, reading them all out of the store the first time.
+// Records are read without holding the cache lock so other lookups aren't stuck behind the store.
+// Records deleted in the meantime are left out and ones that can't be decoded are skipped, so a
+// single bad record doesn't break every listing.
+func allMetadata() ([]clipMetadata, error) {
+	metadataCache.RLock()
+	loaded := metadataCache.loaded
+	metadataCache.RUnlock()
+	if !loaded {
+		names, err := metadataStore.List()
+		if err != nil {
+			return nil, err
+		}
+		metadataCache.RLock()
+		missing := make([]string, 0, len(names))
+		for _, filename := range names {
+			name := strings.TrimSuffix(filename, metadataExt)
+			if _, ok := metadataCache.m[name]; !ok && strings.HasSuffix(filename, metadataExt) {
+				missing = append(missing, filename)
+			}
+		}
+		metadataCache.RUnlock()
+
+		read := make(map[string]clipMetadata, len(missing))
+		for _, filename := range missing {
+			meta, err := readMetadata(filename)
+			if err == errNotFound {
+				continue
+			}
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				log.Printf("Skipping unreadable metadata %v: %v", filename, err)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", filename, err)
+			}
+			read[strings.TrimSuffix(filename, metadataExt)] = meta
+		}
+
+		// Anything cached or deleted in the meantime came from a newer write than what we read
+		metadataCache.Lock()
+		for name, meta := range read {
+			if _, ok := metadataCache.m[name]; !ok && !metadataCache.deleted[name] {
+				metadataCache.m[name] = meta
+			}
+		}
+		metadataCache.loaded = true
+		metadataCache.Unlock()
+	}
+
+	metadataCache.RLock()
+	defer metadataCache.RUnlock()
+	records := make([]clipMetadata, 0, len(metadataCache.m))
+	for _, meta := range metadataCache.m {
+		records = append(records, meta)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+	return records, nil
+}
+
+// tagSound adds and removes the command's tags on the sound, keeping them sorted.
+func tagSound(tagCmd tagCommand) (string, error) {
+	exists, err := soundStore.Exists(tagCmd.name)
+	if err != nil {
+		return "", retryableError(codeStorage, "Error tagging sound", err)
+	}
+	if !exists {
+		return "", newUserError(codeNotFound, "Sound not found")
+	}
+
+	metadataLock.Lock()
+	defer metadataLock.Unlock()
+	meta, err := getMetadata(tagCmd.name)
+	if err == errNotFound {
+		meta = clipMetadata{Name: tagCmd.name}
+	} else if err != nil {
+		return "", retryableError(codeStorage, "Error tagging sound", err)
+	}
+
+	tags := make([]string, 0, len(meta.Tags)+len(tagCmd.add))
+	for _, tag := range append(meta.Tags, tagCmd.add...) {
+		if !containsString(tags, tag) && !containsString(tagCmd.remove, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	meta.Tags = tags
+	err = putMetadata(meta)
+	if err != nil {
+		return "", retryableError(codeStorage, "Error tagging sound", err)
+	}
+
+	if len(tags) == 0 {
+		return tagCmd.name + " has no tags", nil
+	}
+	return tagCmd.name + " is tagged " + strings.Join(tags, ", "), nil
+}
+
+func containsString(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// renameMetadata moves the metadata record for a clip over to its new name, if it has one.
+func renameMetadata(oldName, newName string) error {
+	metadataLock.Lock()
+	defer metadataLock.Unlock()
+	meta, err := getMetadata(oldName)
+	if err == errNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	meta.Name = newName
+	err = putMetadata(meta)
+	if err != nil {
+		return err
+	}
+	return deleteMetadata(oldName)
+}
+
+func newRipMetadata(ripCmd ripCommand, author *discordgo.User, frameCount int) clipMetadata {
+	start, _ := strconv.Atoi(ripCmd.start)
+	duration, _ := strconv.Atoi(ripCmd.duration)
+	meta := clipMetadata{
+		Name:           ripCmd.name,
+		SourceURL:      ripCmd.url,
+		Start:          start,
+		End:            start + duration,
+		DurationFrames: frameCount,
+		Effects:        ripCmd.effects.String(),
+		CreatedAt:      time.Now().UTC(),
+	}
+	if author != nil {
+		meta.CreatedBy = author.Username
+		meta.CreatedByID = author.ID
+	}
+	return meta
+}
+
+// recordPlay bumps the play count of the clip. Clips that predate metadata get a bare record.
+func recordPlay(name string) {
+	metadataLock.Lock()
+	defer metadataLock.Unlock()
+	meta, err := getMetadata(name)
+	if err == errNotFound {
+		meta = clipMetadata{Name: name}
+	} else if err != nil {
+		log.Printf("Failed to get metadata for %v: %v", name, err)
+		return
+	}
+	meta.PlayCount++
+	err = putMetadata(meta)
+	if err != nil {
+		log.Printf("Failed to save metadata for %v: %v", name, err)
+	}
+}
+
+func soundInfo(infoCmd infoCommand) (string, error) {
+	meta, err := getMetadata(infoCmd.name)
+	if err == errNotFound {
+		exists, err := soundStore.Exists(infoCmd.name)
+		if err == nil && !exists {
+			return "", newUserError(codeNotFound, "Sound not found")
+		}
+		return "", newUserError(codeNotFound, "No info recorded for that sound")
+	}
+	if err != nil {
+		return "", retryableError(codeStorage, "Error retrieving sound info", err)
+	}
+	return formatMetadata(meta), nil
+}
+
+func formatMetadata(meta clipMetadata) string {
+	lines := []string{"**" + meta.Name + "**"}
+	if meta.SourceURL != "" {
+		lines = append(lines, fmt.Sprintf("Source: <%v> (%v - %v)", meta.SourceURL, formatSec(meta.Start), formatSec(meta.End)))
+	}
+	if meta.DurationFrames > 0 {
+		length := time.Duration(meta.DurationFrames) * frameDuration
+		lines = append(lines, fmt.Sprintf("Length: %v (%v frames)", length, meta.DurationFrames))
+	}
+	if meta.Effects != "" {
+		lines = append(lines, "Effects: "+meta.Effects)
+	}
+	if meta.CreatedBy != "" {
+		lines = append(lines, fmt.Sprintf("Ripped by %v on %v", meta.CreatedBy, meta.CreatedAt.Format("January 2, 2006")))
+	}
+	if len(meta.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(meta.Tags, ", "))
+	}
+	lines = append(lines, fmt.Sprintf("Played %v times", meta.PlayCount))
+	return strings.Join(lines, "\n")
+}
+
+// formatSec formats seconds back into the XmYs form used by $rip
+func formatSec(sec int) string {
+	return fmt.Sprintf("%vm%vs", sec/60, sec%60)
+}
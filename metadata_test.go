@@ -0,0 +1,117 @@
+package judgego
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRipMetadata(t *testing.T) {
+	ripCmd := ripCommand{"mail", "https://www.youtube.com/watch?v=dFuUCpBbbHw", "61", "9", audioEffects{}}
+	author := &discordgo.User{ID: "123", Username: "colin"}
+
+	meta := newRipMetadata(ripCmd, author, 450)
+
+	assert.Equal(t, "mail", meta.Name)
+	assert.Equal(t, 61, meta.Start)
+	assert.Equal(t, 70, meta.End)
+	assert.Equal(t, 450, meta.DurationFrames)
+	assert.Equal(t, "colin", meta.CreatedBy)
+	assert.Equal(t, "123", meta.CreatedByID)
+}
+
+func TestMetadataFollowsSound(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", []byte("frames"))
+	putMetadata(clipMetadata{Name: "mail", SourceURL: "https://example.com"})
+
+	recordPlay("mail")
+	recordPlay("mail")
+	meta, err := getMetadata("mail")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, meta.PlayCount)
+
+	assert.Nil(t, renameSound(renameCommand{"mail", "letter"}))
+	_, err = getMetadata("mail")
+	assert.Equal(t, errNotFound, err)
+	meta, err = getMetadata("letter")
+	assert.Nil(t, err)
+	assert.Equal(t, "letter", meta.Name)
+	assert.Equal(t, 2, meta.PlayCount)
+
+	assert.Nil(t, deleteSound(deleteCommand{"letter"}))
+	_, err = getMetadata("letter")
+	assert.Equal(t, errNotFound, err)
+}
+
+func TestTagSound(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", nil)
+	store.Put("airhorn", nil)
+	store.Put("sadtrombone", nil)
+	putMetadata(clipMetadata{Name: "sadtrombone", PlayCount: 3, Tags: []string{"sad"}})
+
+	resp, err := tagSound(tagCommand{name: "mail", add: []string{"meme", "classic", "meme"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "mail is tagged classic, meme", resp)
+	resp, err = tagSound(tagCommand{name: "sadtrombone", add: []string{"meme"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "sadtrombone is tagged meme, sad", resp)
+	meta, err := getMetadata("sadtrombone")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, meta.PlayCount)
+
+	_, err = tagSound(tagCommand{name: "missing", add: []string{"meme"}})
+	assert.Equal(t, "Sound not found", userMessage(err))
+
+	resp, err = listSounds(listCommand{tag: "meme"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Sounds tagged meme: mail, sadtrombone", resp)
+	resp, err = listSounds(listCommand{tag: "nope"})
+	assert.Nil(t, err)
+	assert.Equal(t, "No sounds are tagged nope", resp)
+
+	resp, err = tagSound(tagCommand{name: "mail", remove: []string{"meme", "classic"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "mail has no tags", resp)
+	resp, err = listSounds(listCommand{tag: "meme"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Sounds tagged meme: sadtrombone", resp)
+
+	// Records written before the cache was loaded are picked up from the store, and one that
+	// can't be decoded doesn't break the listing
+	metadataStore.Put("broken"+metadataExt, []byte("{"))
+	resetMetadataCache()
+	resp, err = listSounds(listCommand{tag: "sad"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Sounds tagged sad: sadtrombone", resp)
+}
+
+func TestSearchSounds(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("airhorn", nil)
+	store.Put("horn", nil)
+	store.Put("sadtrombone", nil)
+	store.Put("mail", nil)
+	putMetadata(clipMetadata{Name: "sadtrombone", Tags: []string{"brass"}})
+	putMetadata(clipMetadata{Name: "mail", Tags: []string{"hornless"}})
+
+	resp, err := searchSounds(searchCommand{"horn"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Sounds matching horn: horn, airhorn, mail", resp)
+	resp, err = searchSounds(searchCommand{"BRASS"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Sounds matching BRASS: sadtrombone", resp)
+	resp, err = searchSounds(searchCommand{"xylophone"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Nothing matches xylophone", resp)
+}
+
+func TestFormatSec(t *testing.T) {
+	assert.Equal(t, "1m5s", formatSec(65))
+	assert.Equal(t, "0m0s", formatSec(0))
+}
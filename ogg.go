@@ -0,0 +1,274 @@
+package judgego
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Minimal Ogg/Opus muxing and demuxing (RFC 3533 and RFC 7845) for a single logical stream.
+const (
+	oggCapturePattern = "OggS"
+	oggHeaderSize     = 27
+	oggMaxSegments    = 255
+	// oggFlagContinued marks a page whose first packet started on the previous page
+	oggFlagContinued = 0x01
+	oggFlagBOS       = 0x02
+	oggFlagEOS       = 0x04
+	// oggSerial is the stream serial number used for exported clips
+	oggSerial = 0x4a474f43
+	// oggPreSkip is the encoder lookahead of libopus at 48kHz
+	oggPreSkip     = 312
+	oggOpusHead    = "OpusHead"
+	oggOpusTags    = "OpusTags"
+	oggOpusVendor  = "judgego"
+	oggPacketsPage = 50
+)
+
+var (
+	errNotOgg     = errors.New("not an ogg file")
+	errNotOpus    = errors.New("ogg file doesn't contain opus audio")
+	errOggCorrupt = errors.New("ogg file is corrupt")
+)
+
+var oggCRCTable = makeOggCRCTable()
+
+// makeOggCRCTable builds the lookup table for Ogg's unreflected CRC-32 (polynomial 0x04c11db7).
+func makeOggCRCTable() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}
+
+func oggChecksum(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = (crc << 8) ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// oggOpusInfo is what we care about from an OpusHead packet.
+type oggOpusInfo struct {
+	Channels      int
+	PreSkip       int
+	MappingFamily int
+}
+
+// oggWriter packs packets into pages.
+type oggWriter struct {
+	buf      *bytes.Buffer
+	sequence uint32
+}
+
+func (w *oggWriter) writePage(packets [][]byte, granule int64, flags byte) {
+	segments := make([]byte, 0, oggMaxSegments)
+	for _, packet := range packets {
+		segments = append(segments, oggLacing(len(packet))...)
+	}
+
+	page := make([]byte, oggHeaderSize, oggHeaderSize+len(segments))
+	copy(page, oggCapturePattern)
+	page[4] = 0
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:], oggSerial)
+	binary.LittleEndian.PutUint32(page[18:], w.sequence)
+	page[26] = byte(len(segments))
+	page = append(page, segments...)
+	for _, packet := range packets {
+		page = append(page, packet...)
+	}
+	binary.LittleEndian.PutUint32(page[22:], oggChecksum(page))
+
+	w.buf.Write(page)
+	w.sequence++
+}
+
+// oggLacing returns the segment table entries for a packet of the given length.
+func oggLacing(length int) []byte {
+	lacing := make([]byte, 0, length/255+1)
+	for length >= 255 {
+		lacing = append(lacing, 255)
+		length -= 255
+	}
+	return append(lacing, byte(length))
+}
+
+// muxOggOpus wraps our 20ms stereo opus frames in an Ogg/Opus container.
+func muxOggOpus(opusFrames [][]byte) ([]byte, error) {
+	w := &oggWriter{buf: new(bytes.Buffer)}
+
+	head := new(bytes.Buffer)
+	head.WriteString(oggOpusHead)
+	head.WriteByte(1)
+	head.WriteByte(byte(channels))
+	binary.Write(head, binary.LittleEndian, uint16(oggPreSkip))
+	binary.Write(head, binary.LittleEndian, uint32(frameRate))
+	binary.Write(head, binary.LittleEndian, int16(0))
+	head.WriteByte(0)
+	w.writePage([][]byte{head.Bytes()}, 0, oggFlagBOS)
+
+	tags := new(bytes.Buffer)
+	tags.WriteString(oggOpusTags)
+	binary.Write(tags, binary.LittleEndian, uint32(len(oggOpusVendor)))
+	tags.WriteString(oggOpusVendor)
+	binary.Write(tags, binary.LittleEndian, uint32(0))
+	w.writePage([][]byte{tags.Bytes()}, 0, 0)
+
+	granule := int64(oggPreSkip)
+	page := make([][]byte, 0, oggPacketsPage)
+	segments := 0
+	for i, frame := range opusFrames {
+		lacing := len(oggLacing(len(frame)))
+		if lacing > oggMaxSegments {
+			return nil, fmt.Errorf("frame %v is too large for an ogg page", i)
+		}
+		if segments+lacing > oggMaxSegments || len(page) == oggPacketsPage {
+			w.writePage(page, granule, 0)
+			page = page[:0]
+			segments = 0
+		}
+		page = append(page, frame)
+		segments += lacing
+		granule += int64(frameSize)
+	}
+	w.writePage(page, granule, oggFlagEOS)
+
+	return w.buf.Bytes(), nil
+}
+
+// demuxOggOpus pulls the opus packets out of the first logical stream of an Ogg file.
+func demuxOggOpus(data []byte) (oggOpusInfo, [][]byte, error) {
+	var (
+		info    oggOpusInfo
+		serial  uint32
+		started bool
+		partial []byte
+	)
+	packets := make([][]byte, 0)
+
+	for len(data) > 0 {
+		if len(data) < oggHeaderSize || string(data[:4]) != oggCapturePattern {
+			if !started {
+				return info, nil, errNotOgg
+			}
+			return info, nil, errOggCorrupt
+		}
+		segmentCount := int(data[26])
+		if len(data) < oggHeaderSize+segmentCount {
+			return info, nil, errOggCorrupt
+		}
+		segments := data[oggHeaderSize : oggHeaderSize+segmentCount]
+		bodySize := 0
+		for _, s := range segments {
+			bodySize += int(s)
+		}
+		pageSize := oggHeaderSize + segmentCount + bodySize
+		if len(data) < pageSize {
+			return info, nil, errOggCorrupt
+		}
+		page := data[:pageSize]
+		data = data[pageSize:]
+
+		checksum := binary.LittleEndian.Uint32(page[22:])
+		verify := append([]byte{}, page...)
+		binary.LittleEndian.PutUint32(verify[22:], 0)
+		if oggChecksum(verify) != checksum {
+			return info, nil, errOggCorrupt
+		}
+
+		pageSerial := binary.LittleEndian.Uint32(page[14:])
+		if !started {
+			serial = pageSerial
+			started = true
+		} else if pageSerial != serial {
+			// Some other multiplexed stream, we only care about the first one
+			continue
+		}
+
+		body := page[oggHeaderSize+segmentCount:]
+		if page[5]&oggFlagContinued == 0 {
+			partial = nil
+		}
+		for _, s := range segments {
+			partial = append(partial, body[:s]...)
+			body = body[s:]
+			if s < 255 {
+				packets = append(packets, partial)
+				partial = nil
+			}
+		}
+	}
+
+	if len(packets) < 2 || !bytes.HasPrefix(packets[0], []byte(oggOpusHead)) {
+		return info, nil, errNotOpus
+	}
+	head := packets[0]
+	if len(head) < 19 {
+		return info, nil, errOggCorrupt
+	}
+	info.Channels = int(head[9])
+	info.PreSkip = int(binary.LittleEndian.Uint16(head[10:]))
+	info.MappingFamily = int(head[18])
+	if !bytes.HasPrefix(packets[1], []byte(oggOpusTags)) {
+		return info, nil, errNotOpus
+	}
+	return info, packets[2:], nil
+}
+
+// opusPacketSamples returns the number of 48kHz samples encoded in an opus packet based on its TOC byte.
+func opusPacketSamples(packet []byte) int {
+	if len(packet) == 0 {
+		return 0
+	}
+	toc := packet[0]
+	config := int(toc >> 3)
+
+	var frameSamples int
+	switch {
+	case config < 12:
+		frameSamples = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16:
+		frameSamples = []int{480, 960}[config%2]
+	default:
+		frameSamples = []int{120, 240, 480, 960}[config%4]
+	}
+
+	switch toc & 0x03 {
+	case 0:
+		return frameSamples
+	case 1, 2:
+		return frameSamples * 2
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		return frameSamples * int(packet[1]&0x3F)
+	}
+}
+
+// isPlayableOpus reports whether the demuxed packets can be sent to Discord as is, which
+// means every packet has to be a single 20ms frame we can decode in stereo.
+func isPlayableOpus(info oggOpusInfo, packets [][]byte) bool {
+	if info.Channels < 1 || info.Channels > channels || info.MappingFamily != 0 {
+		return false
+	}
+	for _, packet := range packets {
+		if opusPacketSamples(packet) != frameSize {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package judgego
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"layeh.com/gopus"
+)
+
+func encodeSilence(t *testing.T, count int) [][]byte {
+	encoder, err := gopus.NewEncoder(frameRate, channels, gopus.Audio)
+	assert.Nil(t, err)
+	frames := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		frame, err := encoder.Encode(make([]int16, frameSize*channels), frameSize, maxBytes)
+		assert.Nil(t, err)
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestOggRoundTrip(t *testing.T) {
+	frames := encodeSilence(t, 120)
+	// A packet bigger than a single lacing segment
+	frames = append(frames, bytes.Repeat([]byte{0x0c}, 600))
+
+	ogg, err := muxOggOpus(frames)
+	assert.Nil(t, err)
+	assert.Equal(t, oggCapturePattern, string(ogg[:4]))
+
+	info, packets, err := demuxOggOpus(ogg)
+	assert.Nil(t, err)
+	assert.Equal(t, channels, info.Channels)
+	assert.Equal(t, oggPreSkip, info.PreSkip)
+	assert.Equal(t, frames, packets)
+}
+
+func TestOggDemuxRejectsGarbage(t *testing.T) {
+	_, _, err := demuxOggOpus([]byte("definitely not an ogg file"))
+	assert.Equal(t, errNotOgg, err)
+
+	ogg, _ := muxOggOpus(encodeSilence(t, 5))
+	ogg[len(ogg)-1] ^= 0xFF
+	_, _, err = demuxOggOpus(ogg)
+	assert.Equal(t, errOggCorrupt, err)
+}
+
+func TestIsPlayableOpus(t *testing.T) {
+	frames := encodeSilence(t, 3)
+	info := oggOpusInfo{Channels: 2}
+	assert.Equal(t, frameSize, opusPacketSamples(frames[0]))
+	assert.True(t, isPlayableOpus(info, frames))
+
+	// TOC config 3 is a 60ms SILK frame
+	assert.False(t, isPlayableOpus(info, [][]byte{{3 << 3, 0}}))
+	assert.False(t, isPlayableOpus(oggOpusInfo{Channels: 6, MappingFamily: 1}, frames))
+}
+
+func TestImportSoundKeepsExisting(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", []byte("frames"))
+
+	err := importSound(importCommand{name: "mail"}, &discordgo.Message{})
+	assert.Equal(t, "A sound with that name already exists", userMessage(err))
+	data, _ := store.Get("mail")
+	assert.Equal(t, []byte("frames"), data)
+}
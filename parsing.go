@@ -1,11 +1,12 @@
 package judgego
 
 import (
-	"errors"
+	"fmt"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ripCommand contains all pertinent info to resole the $rip command
@@ -14,6 +15,7 @@ type ripCommand struct {
 	url      string
 	start    string
 	duration string
+	effects  audioEffects
 }
 
 // playCommand contains all pertinent info to resole the $play command
@@ -21,8 +23,112 @@ type playCommand struct {
 	name string
 }
 
-// listCommand contains all pertinent info to resolve the $list command (Yes nothing for now)
-type listCommand struct{}
+// listCommand contains all pertinent info to resolve the $list command. An empty tag lists every sound.
+type listCommand struct {
+	tag string
+}
+
+// tagCommand contains all pertinent info to resolve the $tag command
+type tagCommand struct {
+	name   string
+	add    []string
+	remove []string
+}
+
+// searchCommand contains all pertinent info to resolve the $search command
+type searchCommand struct {
+	query string
+}
+
+// randomCommand contains all pertinent info to resolve the $random command. An empty tag picks
+// from every sound and an empty weight picks uniformly.
+type randomCommand struct {
+	tag    string
+	weight string
+}
+
+// shuffleCommand contains all pertinent info to resolve the $shuffle command
+type shuffleCommand struct {
+	count  int
+	tag    string
+	weight string
+}
+
+// uploadCommand contains all pertinent info to resolve the $upload command. The audio comes
+// from the message's attachment, an empty start or duration means use the whole file.
+type uploadCommand struct {
+	name     string
+	start    string
+	duration string
+}
+
+// exportCommand contains all pertinent info to resolve the $export command
+type exportCommand struct {
+	name string
+}
+
+// importCommand contains all pertinent info to resolve the $import command. The audio comes from the message's attachment.
+type importCommand struct {
+	name string
+}
+
+// deleteCommand contains all pertinent info to resolve the $delete command
+type deleteCommand struct {
+	name string
+}
+
+// renameCommand contains all pertinent info to resolve the $rename command
+type renameCommand struct {
+	oldName string
+	newName string
+}
+
+// infoCommand contains all pertinent info to resolve the $info command
+type infoCommand struct {
+	name string
+}
+
+// hallCommand contains all pertinent info to resolve the $hall command
+type hallCommand struct {
+	action string
+	rule   inductionRule
+}
+
+// hallsCommand contains all pertinent info to resolve the $halls command
+type hallsCommand struct {
+	action    string
+	hall      string
+	userID    string
+	self      bool
+	period    time.Duration
+	channelID string
+	since     time.Time
+}
+
+// filterCommand contains all pertinent info to resolve the $filter command
+type filterCommand struct {
+	action string
+	rule   filterRule
+}
+
+// auditCommand contains all pertinent info to resolve the $audit command
+type auditCommand struct {
+	userID string
+	period time.Duration
+	since  time.Time
+}
+
+// queueCommand contains all pertinent info to resolve the $queue command
+type queueCommand struct{}
+
+// skipCommand contains all pertinent info to resolve the $skip command
+type skipCommand struct{}
+
+// stopCommand contains all pertinent info to resolve the $stop command
+type stopCommand struct{}
+
+// clearCommand contains all pertinent info to resolve the $clear command
+type clearCommand struct{}
 
 // messageCommand contains all pertinent info to resolve a normal message (bit of a cheat)
 type messageCommand struct {
@@ -30,31 +136,22 @@ type messageCommand struct {
 }
 
 const (
-	ripPrefix         string = "$rip"
-	ripCmdTokenCount  int    = 5
-	timestampRegex    string = "^\\d+m\\d+s$"
-	playPrefix        string = "$play"
-	playCmdTokenCount int    = 5
-	listPrefix        string = "$list"
+	maxClipSeconds int    = 60
+	timestampRegex string = "^\\d+m\\d+s$"
+	// maxTagLength is the longest a sound's tag can be
+	maxTagLength = 32
 )
 
-// parseMsg parses the message string and returns a struct based on the type of message it is.
-func parseMsg(msg string) (interface{}, error) {
-	var (
-		command interface{}
-		err     error
-	)
-	cmdToken := strings.Split(msg, " ")[0]
-	if cmdToken == ripPrefix {
-		command, err = parseRipCmd(msg)
-	} else if cmdToken == playPrefix {
-		command, err = parsePlayCmd(msg)
-	} else if cmdToken == listPrefix {
-		command, err = parseListCmd(msg)
-	} else {
-		command, err = parseMessageCmd(msg)
-	}
-	return command, err
+var tagRegex = regexp.MustCompile(fmt.Sprintf(`^[a-z0-9_][a-z0-9_-]{0,%v}$`, maxTagLength-1))
+
+// parseMsg parses the message string and returns the registered command it invokes. Anything
+// that isn't a command is a messageCommand.
+func parseMsg(msg string) (Command, error) {
+	cmd, ok := findCommand(strings.Split(msg, " ")[0])
+	if !ok {
+		return parseMessageCmd(msg)
+	}
+	return cmd.Parse(msg)
 }
 
 func parseRipCmd(msg string) (ripCommand, error) {
@@ -62,20 +159,29 @@ func parseRipCmd(msg string) (ripCommand, error) {
 
 	tokens := strings.Split(msg, " ")
 	if len(tokens) < 5 {
-		return cmd, errors.New("Expected 5 tokens, received " + strconv.Itoa(len(tokens)))
+		return cmd, usageError(cmd)
 	}
 	cmd.name = tokens[1]
 
 	if !isValidURL(tokens[2]) {
-		return cmd, errors.New("Invalid URL passed")
+		return cmd, invalidInput("Invalid URL passed")
 	}
 	cmd.url = tokens[2]
 
 	if !isValidTimestamp(tokens[3]) || !isValidTimestamp(tokens[4]) {
-		return cmd, errors.New("Invalid time stamps. Use XmYs form")
+		return cmd, invalidInput("Invalid time stamps. Use XmYs form")
+	}
+	if !isValidClipLength(tokens[3], tokens[4]) {
+		return cmd, invalidInput("End time must be after the start time and clips can be at most " + strconv.Itoa(maxClipSeconds) + " seconds")
 	}
 	cmd.start, cmd.duration = parseAudioLength(tokens[3], tokens[4])
 
+	effects, err := parseEffectFlags(tokens[5:])
+	if err != nil {
+		return cmd, err
+	}
+	cmd.effects = effects
+
 	return cmd, nil
 }
 
@@ -84,7 +190,7 @@ func parsePlayCmd(msg string) (playCommand, error) {
 
 	tokens := strings.Split(msg, " ")
 	if len(tokens) < 2 {
-		return cmd, errors.New("Expected 2 tokens, received " + strconv.Itoa(len(tokens)))
+		return cmd, usageError(cmd)
 	}
 	cmd.name = tokens[1]
 
@@ -92,7 +198,420 @@ func parsePlayCmd(msg string) (playCommand, error) {
 }
 
 func parseListCmd(msg string) (listCommand, error) {
-	return listCommand{}, nil
+	cmd := listCommand{}
+
+	tokens := strings.Fields(msg)
+	if len(tokens) > 2 {
+		return cmd, usageError(cmd)
+	}
+	if len(tokens) == 2 {
+		cmd.tag = strings.ToLower(tokens[1])
+	}
+	return cmd, nil
+}
+
+// parseTagCmd reads the tags to add to the sound. Tags starting with - are removed instead.
+func parseTagCmd(msg string) (tagCommand, error) {
+	cmd := tagCommand{}
+
+	tokens := strings.Fields(msg)
+	if len(tokens) < 3 {
+		return cmd, usageError(cmd)
+	}
+	cmd.name = tokens[1]
+	for _, token := range tokens[2:] {
+		tag := strings.ToLower(strings.TrimPrefix(token, "-"))
+		if !tagRegex.MatchString(tag) {
+			return cmd, invalidInput(fmt.Sprintf("Tags can be at most %v letters, numbers, - and _", maxTagLength))
+		}
+		if strings.HasPrefix(token, "-") {
+			cmd.remove = append(cmd.remove, tag)
+		} else {
+			cmd.add = append(cmd.add, tag)
+		}
+	}
+	return cmd, nil
+}
+
+func parseRandomCmd(msg string) (randomCommand, error) {
+	cmd := randomCommand{}
+
+	tag, weight, ok := parsePickTokens(strings.Fields(msg)[1:])
+	if !ok {
+		return cmd, usageError(cmd)
+	}
+	cmd.tag, cmd.weight = tag, weight
+	return cmd, nil
+}
+
+func parseShuffleCmd(msg string) (shuffleCommand, error) {
+	cmd := shuffleCommand{}
+
+	tokens := strings.Fields(msg)
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	count, err := strconv.Atoi(tokens[1])
+	if err != nil || count < 1 || count > maxShuffle {
+		return cmd, invalidInput(fmt.Sprintf("You can shuffle 1 to %v sounds", maxShuffle))
+	}
+	tag, weight, ok := parsePickTokens(tokens[2:])
+	if !ok {
+		return cmd, usageError(cmd)
+	}
+	cmd.count, cmd.tag, cmd.weight = count, tag, weight
+	return cmd, nil
+}
+
+// parsePickTokens reads the optional tag and --weight flag random picks take, in either order.
+func parsePickTokens(tokens []string) (tag string, weight string, ok bool) {
+	for i := 0; i < len(tokens); i++ {
+		switch {
+		case tokens[i] == "--weight" && i+1 < len(tokens):
+			i++
+			weight = strings.ToLower(tokens[i])
+			if weight != weightPlays && weight != weightRecent {
+				return "", "", false
+			}
+		case tag == "" && !strings.HasPrefix(tokens[i], "--"):
+			tag = strings.ToLower(tokens[i])
+		default:
+			return "", "", false
+		}
+	}
+	return tag, weight, true
+}
+
+func parseSearchCmd(msg string) (searchCommand, error) {
+	cmd := searchCommand{}
+
+	tokens := strings.Fields(msg)
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	cmd.query = strings.Join(tokens[1:], " ")
+	return cmd, nil
+}
+
+func parseUploadCmd(msg string) (uploadCommand, error) {
+	cmd := uploadCommand{}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	cmd.name = tokens[1]
+
+	switch len(tokens) {
+	case 2:
+	case 3:
+		if !isValidTimestamp(tokens[2]) {
+			return cmd, invalidInput("Invalid time stamps. Use XmYs form")
+		}
+		cmd.start = strconv.Itoa(convertTimeToSec(tokens[2]))
+	default:
+		if !isValidTimestamp(tokens[2]) || !isValidTimestamp(tokens[3]) {
+			return cmd, invalidInput("Invalid time stamps. Use XmYs form")
+		}
+		if !isValidClipLength(tokens[2], tokens[3]) {
+			return cmd, invalidInput("End time must be after the start time and clips can be at most " + strconv.Itoa(maxClipSeconds) + " seconds")
+		}
+		cmd.start, cmd.duration = parseAudioLength(tokens[2], tokens[3])
+	}
+
+	return cmd, nil
+}
+
+func parseExportCmd(msg string) (exportCommand, error) {
+	cmd := exportCommand{}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	cmd.name = tokens[1]
+
+	return cmd, nil
+}
+
+func parseImportCmd(msg string) (importCommand, error) {
+	cmd := importCommand{}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	cmd.name = tokens[1]
+
+	return cmd, nil
+}
+
+func parseDeleteCmd(msg string) (deleteCommand, error) {
+	cmd := deleteCommand{}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	cmd.name = tokens[1]
+
+	return cmd, nil
+}
+
+func parseRenameCmd(msg string) (renameCommand, error) {
+	cmd := renameCommand{}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 3 {
+		return cmd, usageError(cmd)
+	}
+	cmd.oldName = tokens[1]
+	cmd.newName = tokens[2]
+
+	return cmd, nil
+}
+
+func parseInfoCmd(msg string) (infoCommand, error) {
+	cmd := infoCommand{}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 2 {
+		return cmd, usageError(cmd)
+	}
+	cmd.name = tokens[1]
+
+	return cmd, nil
+}
+
+func parseHallCmd(msg string) (hallCommand, error) {
+	cmd := hallCommand{action: "list"}
+
+	tokens := strings.Split(msg, " ")
+	if len(tokens) < 2 {
+		return cmd, nil
+	}
+	cmd.action = tokens[1]
+
+	switch cmd.action {
+	case "list":
+	case "remove":
+		if len(tokens) < 3 {
+			return cmd, usageError(cmd)
+		}
+		cmd.rule.Name = tokens[2]
+	case "add":
+		if len(tokens) < 6 {
+			return cmd, usageError(cmd)
+		}
+		cmd.rule.Name = tokens[2]
+		cmd.rule.Emoji = parseEmoji(tokens[3])
+
+		threshold, err := strconv.Atoi(tokens[4])
+		if err != nil || threshold < 1 {
+			return cmd, invalidInput("Threshold must be a positive number")
+		}
+		cmd.rule.Threshold = threshold
+
+		channelID, ok := parseChannelMention(tokens[5])
+		if !ok {
+			return cmd, invalidInput("Invalid channel. Mention it like #hall-of-fame")
+		}
+		cmd.rule.ChannelID = channelID
+
+		rest := tokens[6:]
+		for len(rest) > 0 && (rest[0] == countAuthorFlag || rest[0] == reversalFlag) {
+			if rest[0] == countAuthorFlag {
+				cmd.rule.CountAuthor = true
+				rest = rest[1:]
+				continue
+			}
+			if len(rest) < 2 {
+				return cmd, invalidInput(reversalFlag + " needs keep, edit or remove")
+			}
+			switch rest[1] {
+			case reversalKeep, reversalEdit, reversalRemove:
+				cmd.rule.Reversal = rest[1]
+			default:
+				return cmd, invalidInput("Unknown reversal " + rest[1] + ". Use keep, edit or remove")
+			}
+			rest = rest[2:]
+		}
+		cmd.rule.Template = strings.Replace(strings.Join(rest, " "), `\n`, "\n", -1)
+		if cmd.rule.Template == "" {
+			cmd.rule.Template = fameTemplate
+		}
+	default:
+		return cmd, invalidInput("Unknown hall action " + cmd.action + ". Use add, remove or list")
+	}
+
+	return cmd, nil
+}
+
+// parseHallsCmd parses $halls [top [hall]|me|@user] [period] where period is week, month,
+// year, all or a number of days like 14d, and $halls backfill.
+func parseHallsCmd(msg string) (hallsCommand, error) {
+	cmd := hallsCommand{action: "top"}
+
+	tokens := strings.Fields(msg)[1:]
+	if len(tokens) > 0 && tokens[0] == "backfill" {
+		return parseBackfillTokens(tokens[1:])
+	}
+	if len(tokens) > 0 {
+		if userID, ok := parseUserMention(tokens[0]); ok {
+			cmd.action = "user"
+			cmd.userID = userID
+			tokens = tokens[1:]
+		} else if tokens[0] == "me" {
+			cmd.action = "user"
+			cmd.self = true
+			tokens = tokens[1:]
+		} else if tokens[0] == "top" {
+			tokens = tokens[1:]
+			if len(tokens) > 0 {
+				if _, ok := parsePeriod(tokens[0]); !ok {
+					cmd.hall = tokens[0]
+					tokens = tokens[1:]
+				}
+			}
+		}
+	}
+
+	if len(tokens) > 0 {
+		period, ok := parsePeriod(tokens[0])
+		if !ok {
+			return cmd, invalidInput("Unknown period " + tokens[0] + ". Use week, month, year, all or a number of days like 14d")
+		}
+		cmd.period = period
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 {
+		return cmd, usageError(cmd)
+	}
+	return cmd, nil
+}
+
+// parseBackfillTokens parses the rest of $halls backfill <#channel> [since YYYY-MM-DD].
+func parseBackfillTokens(tokens []string) (hallsCommand, error) {
+	cmd := hallsCommand{action: "backfill"}
+	usage := usageError(cmd)
+	if len(tokens) < 1 {
+		return cmd, usage
+	}
+	channelID, ok := parseChannelMention(tokens[0])
+	if !ok {
+		return cmd, invalidInput("Invalid channel. Mention it like #general")
+	}
+	cmd.channelID = channelID
+
+	tokens = tokens[1:]
+	if len(tokens) > 0 && tokens[0] == "since" {
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return cmd, usage
+		}
+	}
+	if len(tokens) > 1 {
+		return cmd, usage
+	}
+	if len(tokens) == 1 {
+		since, err := time.Parse(backfillDateFormat, tokens[0])
+		if err != nil {
+			return cmd, invalidInput("Invalid date " + tokens[0] + ". Use a form like " + backfillDateFormat)
+		}
+		cmd.since = since
+	}
+	return cmd, nil
+}
+
+// parseUserMention accepts a user mention like <@id> or <@!id>.
+func parseUserMention(token string) (string, bool) {
+	if !strings.HasPrefix(token, "<@") || !strings.HasSuffix(token, ">") {
+		return "", false
+	}
+	id := strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(token, "<@"), ">"), "!")
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// parseFilterCmd parses $filter add <name> <word|regex> <pattern> [actions...], $filter remove <name>
+// and $filter list. Actions default to warn.
+func parseFilterCmd(msg string) (filterCommand, error) {
+	cmd := filterCommand{action: "list"}
+
+	tokens := strings.Fields(msg)
+	if len(tokens) < 2 {
+		return cmd, nil
+	}
+	cmd.action = tokens[1]
+
+	switch cmd.action {
+	case "list":
+	case "remove":
+		if len(tokens) < 3 {
+			return cmd, usageError(cmd)
+		}
+		cmd.rule.Name = tokens[2]
+	case "add":
+		if len(tokens) < 5 {
+			return cmd, usageError(cmd)
+		}
+		cmd.rule.Name = tokens[2]
+		cmd.rule.Type = strings.ToLower(tokens[3])
+		if cmd.rule.Type != filterWord && cmd.rule.Type != filterRegex {
+			return cmd, invalidInput("Filter type must be word or regex")
+		}
+		cmd.rule.Pattern = tokens[4]
+		err := cmd.rule.compile()
+		if err != nil {
+			return cmd, invalidInput("Invalid regex: " + err.Error())
+		}
+
+		cmd.rule.Actions = []string{actionWarn}
+		if len(tokens) > 5 {
+			cmd.rule.Actions = make([]string, 0, len(tokens)-5)
+			for _, action := range tokens[5:] {
+				action = strings.ToLower(action)
+				if !isFilterAction(action) {
+					return cmd, invalidInput("Unknown action " + action + ". Use " + strings.Join(filterActions, ", "))
+				}
+				cmd.rule.Actions = append(cmd.rule.Actions, action)
+			}
+		}
+	default:
+		return cmd, invalidInput("Unknown filter action " + cmd.action + ". Use add, remove or list")
+	}
+
+	return cmd, nil
+}
+
+// parseAuditCmd parses $audit [@user] [since] where since is a period like 7d or week, or a
+// date like 2020-03-01.
+func parseAuditCmd(msg string) (auditCommand, error) {
+	cmd := auditCommand{}
+	usage := usageError(cmd)
+
+	tokens := strings.Fields(msg)[1:]
+	if len(tokens) > 0 {
+		if userID, ok := parseUserMention(tokens[0]); ok {
+			cmd.userID = userID
+			tokens = tokens[1:]
+		}
+	}
+	if len(tokens) > 1 {
+		return cmd, usage
+	}
+	if len(tokens) == 1 {
+		if period, ok := parsePeriod(tokens[0]); ok {
+			cmd.period = period
+		} else if since, err := time.Parse(backfillDateFormat, tokens[0]); err == nil {
+			cmd.since = since
+		} else {
+			return cmd, usage
+		}
+	}
+	return cmd, nil
 }
 
 func parseMessageCmd(msg string) (messageCommand, error) {
@@ -116,6 +635,11 @@ func isValidTimestamp(timestamp string) bool {
 	return false
 }
 
+func isValidClipLength(start string, end string) bool {
+	length := convertTimeToSec(end) - convertTimeToSec(start)
+	return length > 0 && length <= maxClipSeconds
+}
+
 func parseAudioLength(start string, end string) (string, string) {
 	startSec := convertTimeToSec(start)
 	endSec := convertTimeToSec(end)
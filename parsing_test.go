@@ -29,7 +29,7 @@ func TestParseRipCmd(t *testing.T) {
 	parsedRipCmd, err := parseRipCmd(ripCmd)
 
 	assert.Nil(t, err)
-	assert.Equal(t, parsedRipCmd, ripCommand{"testName", "https://www.youtube.com/watch?v=dFuUCpBbbHw", "1", "9"})
+	assert.Equal(t, parsedRipCmd, ripCommand{"testName", "https://www.youtube.com/watch?v=dFuUCpBbbHw", "1", "9", audioEffects{}})
 }
 func TestParseRipCmdMissingToken(t *testing.T) {
 	for _, cmd := range parseRipCmdFailTable {
@@ -64,3 +64,104 @@ func TestConvertTimeToSec(t *testing.T) {
 		assert.Equal(t, convertedTimestamp, testData.out)
 	}
 }
+
+func TestParseRenameCmd(t *testing.T) {
+	parsedRenameCmd, err := parseRenameCmd("$rename mail letter")
+
+	assert.Nil(t, err)
+	assert.Equal(t, parsedRenameCmd, renameCommand{"mail", "letter"})
+
+	_, err = parseRenameCmd("$rename mail")
+	assert.NotNil(t, err)
+}
+
+var parseUploadCmdTable = []struct {
+	in  string
+	out uploadCommand
+}{
+	{"$upload memo", uploadCommand{"memo", "", ""}},
+	{"$upload memo 0m5s", uploadCommand{"memo", "5", ""}},
+	{"$upload memo 0m5s 0m8s", uploadCommand{"memo", "5", "3"}},
+}
+
+func TestParseUploadCmd(t *testing.T) {
+	for _, testData := range parseUploadCmdTable {
+		parsedUploadCmd, err := parseUploadCmd(testData.in)
+		assert.Nil(t, err)
+		assert.Equal(t, parsedUploadCmd, testData.out)
+	}
+
+	_, err := parseUploadCmd("$upload memo 5s")
+	assert.NotNil(t, err)
+}
+
+func TestParseRipCmdClipLength(t *testing.T) {
+	_, err := parseRipCmd("$rip testName https://www.youtube.com/watch?v=dFuUCpBbbHw 0m10s 0m5s")
+	assert.NotNil(t, err)
+
+	_, err = parseRipCmd("$rip testName https://www.youtube.com/watch?v=dFuUCpBbbHw 0m0s 5m0s")
+	assert.NotNil(t, err)
+}
+
+func TestParseTagCmd(t *testing.T) {
+	parsedTagCmd, err := parseTagCmd("$tag mail Meme -old classic")
+
+	assert.Nil(t, err)
+	assert.Equal(t, tagCommand{"mail", []string{"meme", "classic"}, []string{"old"}}, parsedTagCmd)
+
+	_, err = parseTagCmd("$tag mail")
+	assert.NotNil(t, err)
+
+	_, err = parseTagCmd("$tag mail no/slashes")
+	assert.NotNil(t, err)
+}
+
+func TestParseListCmd(t *testing.T) {
+	parsedListCmd, err := parseListCmd("$list")
+	assert.Nil(t, err)
+	assert.Equal(t, listCommand{}, parsedListCmd)
+
+	parsedListCmd, err = parseListCmd("$list Meme")
+	assert.Nil(t, err)
+	assert.Equal(t, listCommand{"meme"}, parsedListCmd)
+
+	_, err = parseListCmd("$list meme classic")
+	assert.NotNil(t, err)
+}
+
+func TestParseSearchCmd(t *testing.T) {
+	parsedSearchCmd, err := parseSearchCmd("$search sad  trombone")
+	assert.Nil(t, err)
+	assert.Equal(t, searchCommand{"sad trombone"}, parsedSearchCmd)
+
+	_, err = parseSearchCmd("$search")
+	assert.NotNil(t, err)
+}
+
+func TestParseRandomCmd(t *testing.T) {
+	parsedRandomCmd, err := parseRandomCmd("$random")
+	assert.Nil(t, err)
+	assert.Equal(t, randomCommand{}, parsedRandomCmd)
+
+	parsedRandomCmd, err = parseRandomCmd("$random --weight Plays Meme")
+	assert.Nil(t, err)
+	assert.Equal(t, randomCommand{"meme", weightPlays}, parsedRandomCmd)
+
+	_, err = parseRandomCmd("$random meme classic")
+	assert.NotNil(t, err)
+	_, err = parseRandomCmd("$random --weight loudest")
+	assert.NotNil(t, err)
+}
+
+func TestParseShuffleCmd(t *testing.T) {
+	parsedShuffleCmd, err := parseShuffleCmd("$shuffle 3 meme --weight recent")
+	assert.Nil(t, err)
+	assert.Equal(t, shuffleCommand{3, "meme", weightRecent}, parsedShuffleCmd)
+
+	_, err = parseShuffleCmd("$shuffle")
+	assert.NotNil(t, err)
+	_, err = parseShuffleCmd("$shuffle 0")
+	assert.NotNil(t, err)
+	_, err = parseShuffleCmd("$shuffle many")
+	assert.NotNil(t, err)
+}
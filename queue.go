@@ -0,0 +1,285 @@
+package judgego
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// voiceIdleTimeout is how long the bot hangs around in voice after its queue runs dry
+const voiceIdleTimeout = 2 * time.Minute
+
+// queuedSound is a single entry in a guild's playback queue.
+type queuedSound struct {
+	name          string
+	audio         [][]byte
+	voiceChanID   string
+	textChannelID string
+}
+
+// guildPlayer owns the voice connection and playback queue for a single guild. Sounds are
+// played back to back in FIFO order by the run goroutine.
+type guildPlayer struct {
+	sync.Mutex
+	guildID string
+	queue   []*queuedSound
+	current *queuedSound
+	leave   bool
+	vc      *discordgo.VoiceConnection
+	wake    chan struct{}
+	skip    chan struct{}
+}
+
+var players = struct {
+	sync.Mutex
+	m map[string]*guildPlayer
+}{m: make(map[string]*guildPlayer)}
+
+func newGuildPlayer(guildID string) *guildPlayer {
+	return &guildPlayer{
+		guildID: guildID,
+		queue:   make([]*queuedSound, 0),
+		wake:    make(chan struct{}, 1),
+		skip:    make(chan struct{}, 1),
+	}
+}
+
+// getPlayer returns the player for the guild, starting one if it doesn't exist yet.
+func getPlayer(s *discordgo.Session, guildID string) *guildPlayer {
+	players.Lock()
+	defer players.Unlock()
+	p, ok := players.m[guildID]
+	if !ok {
+		p = newGuildPlayer(guildID)
+		players.m[guildID] = p
+		go p.run(s)
+	}
+	return p
+}
+
+// queueSounds adds the sounds to the guild's queue in order, targeting the voice channel the
+// author is in.
+func queueSounds(ctx *commandContext, sounds []*queuedSound) error {
+	vs, err := findUserVoiceState(ctx.session, ctx.author.ID)
+	if err != nil {
+		return newUserError(codeNotFound, "Couldn't find user voice channel")
+	}
+	for _, snd := range sounds {
+		snd.voiceChanID = vs.ChannelID
+		snd.textChannelID = ctx.channelID
+	}
+	getPlayer(ctx.session, ctx.guildID).enqueue(sounds...)
+	return nil
+}
+
+// recordPlays counts the sounds as played. Only sounds that made it into the queue count.
+func recordPlays(sounds []*queuedSound) {
+	for _, snd := range sounds {
+		go recordPlay(snd.name)
+	}
+}
+
+func queuedNames(sounds []*queuedSound) string {
+	names := make([]string, 0, len(sounds))
+	for _, snd := range sounds {
+		names = append(names, snd.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// enqueue adds the sounds to the back of the queue together, so nothing queued at the same time
+// ends up in between them.
+func (p *guildPlayer) enqueue(snds ...*queuedSound) {
+	p.Lock()
+	p.queue = append(p.queue, snds...)
+	p.leave = false
+	p.Unlock()
+	notify(p.wake)
+}
+
+// next pops the next sound off the queue and marks it as current. Returns nil if the queue is empty.
+func (p *guildPlayer) next() *queuedSound {
+	p.Lock()
+	defer p.Unlock()
+	p.current = nil
+	if len(p.queue) == 0 {
+		return nil
+	}
+	p.current = p.queue[0]
+	p.queue = p.queue[1:]
+	return p.current
+}
+
+// clear drops everything waiting in the queue and returns how many sounds were removed.
+func (p *guildPlayer) clear() int {
+	p.Lock()
+	defer p.Unlock()
+	count := len(p.queue)
+	p.queue = make([]*queuedSound, 0)
+	return count
+}
+
+// skipCurrent stops the current sound, returning false if nothing was playing.
+func (p *guildPlayer) skipCurrent() bool {
+	p.Lock()
+	playing := p.current != nil
+	p.Unlock()
+	if playing {
+		notify(p.skip)
+	}
+	return playing
+}
+
+// stop clears the queue, stops the current sound and leaves the voice channel.
+func (p *guildPlayer) stop() {
+	p.clear()
+	p.Lock()
+	p.leave = true
+	p.Unlock()
+	notify(p.skip)
+	notify(p.wake)
+}
+
+// snapshot returns the currently playing sound name and the names of everything queued up.
+func (p *guildPlayer) snapshot() (string, []string) {
+	p.Lock()
+	defer p.Unlock()
+	current := ""
+	if p.current != nil {
+		current = p.current.name
+	}
+	names := make([]string, 0, len(p.queue))
+	for _, snd := range p.queue {
+		names = append(names, snd.name)
+	}
+	return current, names
+}
+
+func (p *guildPlayer) shouldLeave() bool {
+	p.Lock()
+	defer p.Unlock()
+	leave := p.leave
+	p.leave = false
+	return leave
+}
+
+func (p *guildPlayer) run(s *discordgo.Session) {
+	for {
+		snd := p.next()
+		if snd == nil {
+			if p.shouldLeave() {
+				p.disconnect()
+			}
+			select {
+			case <-p.wake:
+			case <-time.After(voiceIdleTimeout):
+				p.disconnect()
+				<-p.wake
+			}
+			continue
+		}
+
+		err := p.play(s, snd)
+		if err != nil {
+			logError(fmt.Sprintf("Playing %v in guild %v", snd.name, p.guildID), err)
+			s.ChannelMessageSend(snd.textChannelID, userMessage(err))
+		}
+	}
+}
+
+func (p *guildPlayer) play(s *discordgo.Session, snd *queuedSound) error {
+	// Throw away any skip requested while nothing was playing
+	drain(p.skip)
+
+	if p.vc == nil || p.vc.ChannelID != snd.voiceChanID {
+		vc, err := s.ChannelVoiceJoin(p.guildID, snd.voiceChanID, false, true)
+		if err != nil {
+			return retryableError(codeDiscord, "Couldn't join voice channel", err)
+		}
+		p.vc = vc
+	}
+
+	err := p.vc.Speaking(true)
+	if err != nil {
+		log.Println("Couldn't set speaking: ", err)
+	}
+
+	defer func() {
+		err := p.vc.Speaking(false)
+		if err != nil {
+			log.Println("Couldn't stop speaking: ", err)
+		}
+	}()
+
+	for _, byteArray := range snd.audio {
+		select {
+		case p.vc.OpusSend <- byteArray:
+		case <-p.skip:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *guildPlayer) disconnect() {
+	if p.vc == nil {
+		return
+	}
+	err := p.vc.Disconnect()
+	if err != nil {
+		log.Println("Couldn't disconnect from voice: ", err)
+	}
+	p.vc = nil
+}
+
+// notify does a non-blocking send on a signal channel.
+func notify(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+func drain(c chan struct{}) {
+	select {
+	case <-c:
+	default:
+	}
+}
+
+func showQueue(s *discordgo.Session, guildID string) string {
+	current, queued := getPlayer(s, guildID).snapshot()
+	if current == "" && len(queued) == 0 {
+		return "Nothing is queued up."
+	}
+
+	var sb strings.Builder
+	if current != "" {
+		sb.WriteString("Now playing: " + current + "\n")
+	}
+	for i, name := range queued {
+		sb.WriteString(fmt.Sprintf("%v. %v\n", i+1, name))
+	}
+	return sb.String()
+}
+
+func skipSound(s *discordgo.Session, guildID string) string {
+	if !getPlayer(s, guildID).skipCurrent() {
+		return "Nothing is playing."
+	}
+	return "Skipped."
+}
+
+func stopPlayback(s *discordgo.Session, guildID string) string {
+	getPlayer(s, guildID).stop()
+	return "Stopped playback and cleared the queue."
+}
+
+func clearQueue(s *discordgo.Session, guildID string) string {
+	count := getPlayer(s, guildID).clear()
+	return fmt.Sprintf("Removed %v sound(s) from the queue.", count)
+}
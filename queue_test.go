@@ -0,0 +1,38 @@
+package judgego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuildPlayerQueueOrder(t *testing.T) {
+	p := newGuildPlayer("guild")
+	p.enqueue(&queuedSound{name: "first"})
+	p.enqueue(&queuedSound{name: "second"})
+	p.enqueue(&queuedSound{name: "third"})
+
+	assert.Equal(t, "first", p.next().name)
+	current, queued := p.snapshot()
+	assert.Equal(t, "first", current)
+	assert.Equal(t, []string{"second", "third"}, queued)
+
+	assert.Equal(t, "second", p.next().name)
+	assert.Equal(t, "third", p.next().name)
+	assert.Nil(t, p.next())
+}
+
+func TestGuildPlayerClear(t *testing.T) {
+	p := newGuildPlayer("guild")
+	p.enqueue(&queuedSound{name: "first"})
+	p.enqueue(&queuedSound{name: "second"})
+	p.next()
+
+	assert.Equal(t, 1, p.clear())
+	current, queued := p.snapshot()
+	assert.Equal(t, "first", current)
+	assert.Empty(t, queued)
+	assert.True(t, p.skipCurrent())
+	assert.Nil(t, p.next())
+	assert.False(t, p.skipCurrent())
+}
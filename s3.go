@@ -2,128 +2,145 @@ package judgego
 
 import (
 	"bytes"
-	"errors"
+	"net/http"
 	"os"
-	"regexp"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 const (
-	// soundClipRegex is the regex used to pull sound clip names from the bucket
-	soundClipRegex  = audioFilePrefix + "(.+)"
+	// audioFilePrefix is the bucket prefix sound clips are stored under
 	audioFilePrefix = "sound-clips/"
 )
 
 var bucketName string = os.Getenv("BUCKET_NAME")
 
-// TODO: This entire file can be genericized a bit, there is a mix of app specific code and generic functionality.
-
-func listSoundsS3() ([]string, error) {
-	sess, err := session.NewSession(&aws.Config{
+func newAWSSession() (*session.Session, error) {
+	return session.NewSession(&aws.Config{
 		Region: aws.String("us-east-1")},
 	)
-	if err != nil {
-		return nil, errors.New("Unable to access AWS")
-	}
-	svc := s3.New(sess)
+}
 
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(bucketName)})
-	if err != nil {
-		return nil, errors.New("Unable to access sound bucket")
+// isS3NotFound reports whether err is S3 telling us the key doesn't exist.
+func isS3NotFound(err error) bool {
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() == http.StatusNotFound
 	}
-
-	sounds := make([]string, 0)
-	re := regexp.MustCompile(soundClipRegex)
-	for _, item := range resp.Contents {
-		matches := re.FindSubmatch([]byte(*item.Key))
-		if matches != nil {
-			sounds = append(sounds, string(matches[1]))
-		}
-	}
-	return sounds, nil
+	return false
 }
 
-func putSoundS3(sound *bytes.Buffer, name string) error {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1")},
-	)
-	if err != nil {
-		return err
-	}
+// s3Store is a SoundStore backed by an S3 bucket. Every sound is stored under prefix.
+type s3Store struct {
+	bucket string
+	prefix string
+	sess   *session.Session
+}
 
-	uploader := s3manager.NewUploader(sess)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(audioFilePrefix + name),
-		Body:   sound,
-	})
+func newS3Store(bucket, prefix string) (*s3Store, error) {
+	sess, err := newAWSSession()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &s3Store{bucket: bucket, prefix: prefix, sess: sess}, nil
+}
 
-	return nil
+func (s *s3Store) key(name string) *string {
+	return aws.String(s.prefix + name)
 }
 
-func getSoundS3(name string) []byte {
-	sess, _ := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1")},
-	)
-	downloader := s3manager.NewDownloader(sess)
+func (s *s3Store) Get(name string) ([]byte, error) {
+	downloader := s3manager.NewDownloader(s.sess)
 
 	buf := aws.NewWriteAtBuffer([]byte{})
-	_, _ = downloader.Download(buf,
+	_, err := downloader.Download(buf,
 		&s3.GetObjectInput{
-			Bucket: aws.String(bucketName),
-			Key:    aws.String(audioFilePrefix + name),
+			Bucket: aws.String(s.bucket),
+			Key:    s.key(name),
 		})
-	return buf.Bytes()
+	if isS3NotFound(err) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-func writeToS3(b *bytes.Buffer, name string) error {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1")},
-	)
+func (s *s3Store) Put(name string, data []byte) error {
+	uploader := s3manager.NewUploader(s.sess)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(name),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *s3Store) List() ([]string, error) {
+	svc := s3.New(s.sess)
+
+	sounds := make([]string, 0)
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket), Prefix: aws.String(s.prefix)}
+	err := svc.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			name := strings.TrimPrefix(*item.Key, s.prefix)
+			if name != "" {
+				sounds = append(sounds, name)
+			}
+		}
+		return true
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return sounds, nil
+}
 
-	uploader := s3manager.NewUploader(sess)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(name),
-		Body:   b,
-	})
+func (s *s3Store) Delete(name string) error {
+	exists, err := s.Exists(name)
 	if err != nil {
 		return err
 	}
+	if !exists {
+		return errNotFound
+	}
 
-	return nil
-
+	svc := s3.New(s.sess)
+	_, err = svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(name),
+	})
+	return err
 }
 
-func getFromS3(name string) ([]byte, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1")},
-	)
-	if err != nil {
-		return nil, err
+func (s *s3Store) Exists(name string) (bool, error) {
+	_, err := s.Stat(name)
+	if err == errNotFound {
+		return false, nil
 	}
+	return err == nil, err
+}
 
-	downloader := s3manager.NewDownloader(sess)
-
-	buf := aws.NewWriteAtBuffer([]byte{})
-	_, err = downloader.Download(buf,
-		&s3.GetObjectInput{
-			Bucket: aws.String(bucketName),
-			Key:    aws.String(name),
-		})
+func (s *s3Store) Stat(name string) (SoundInfo, error) {
+	svc := s3.New(s.sess)
+	head, err := svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    s.key(name),
+	})
+	if isS3NotFound(err) {
+		return SoundInfo{}, errNotFound
+	}
 	if err != nil {
-		return nil, err
+		return SoundInfo{}, err
 	}
-
-	return buf.Bytes(), nil
+	return SoundInfo{
+		Name:    name,
+		Size:    aws.Int64Value(head.ContentLength),
+		ModTime: aws.TimeValue(head.LastModified),
+	}, nil
 }
@@ -0,0 +1,131 @@
+package judgego
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// maxShuffle is the most sounds $shuffle will queue at once
+	maxShuffle = 10
+	// recencyHalfLife is how long it takes a sound's chance of being picked by recency to halve
+	recencyHalfLife = 30 * day
+	// minPickWeight keeps old and unplayed sounds in the running
+	minPickWeight = 0.01
+
+	// How random picks are weighted. Without a weight every sound is equally likely.
+	weightPlays  = "plays"
+	weightRecent = "recent"
+)
+
+// pickRand is the source random picks are made with. A *rand.Rand isn't safe to share.
+var pickRand = struct {
+	sync.Mutex
+	r *rand.Rand
+}{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func randFloat() float64 {
+	pickRand.Lock()
+	defer pickRand.Unlock()
+	return pickRand.r.Float64()
+}
+
+// pickCandidates returns the metadata of every sound with the tag, or of every sound if tag
+// is empty. Sounds without a metadata record get a bare one.
+func pickCandidates(tag string) ([]clipMetadata, error) {
+	sounds, err := soundStore.List()
+	if err != nil {
+		return nil, err
+	}
+	records, err := allMetadata()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]clipMetadata, len(records))
+	for _, meta := range records {
+		byName[meta.Name] = meta
+	}
+
+	candidates := make([]clipMetadata, 0, len(sounds))
+	for _, sound := range sounds {
+		meta, ok := byName[sound]
+		if !ok {
+			meta = clipMetadata{Name: sound}
+		}
+		if tag == "" || meta.hasTag(tag) {
+			candidates = append(candidates, meta)
+		}
+	}
+	return candidates, nil
+}
+
+// pickWeight is how likely the sound is to be picked relative to the others.
+func pickWeight(meta clipMetadata, weight string, now time.Time) float64 {
+	switch weight {
+	case weightPlays:
+		return float64(1 + meta.PlayCount)
+	case weightRecent:
+		if meta.CreatedAt.IsZero() {
+			return minPickWeight
+		}
+		halvings := float64(now.Sub(meta.CreatedAt)) / float64(recencyHalfLife)
+		return math.Max(math.Pow(0.5, halvings), minPickWeight)
+	default:
+		return 1
+	}
+}
+
+// pickSounds draws up to n different sounds out of the candidates. random returns a number in [0, 1).
+func pickSounds(candidates []clipMetadata, n int, weight string, now time.Time, random func() float64) []string {
+	remaining := append([]clipMetadata{}, candidates...)
+	weights := make([]float64, len(remaining))
+	for i, meta := range remaining {
+		weights[i] = pickWeight(meta, weight, now)
+	}
+
+	picked := make([]string, 0, n)
+	for len(picked) < n && len(remaining) > 0 {
+		total := 0.0
+		for _, w := range weights {
+			total += w
+		}
+		target := random() * total
+		i := 0
+		for ; i < len(weights)-1; i++ {
+			target -= weights[i]
+			if target < 0 {
+				break
+			}
+		}
+		picked = append(picked, remaining[i].Name)
+		remaining = append(remaining[:i], remaining[i+1:]...)
+		weights = append(weights[:i], weights[i+1:]...)
+	}
+	return picked
+}
+
+// shuffleSounds loads n randomly picked sounds with the tag, ready to be queued.
+func shuffleSounds(n int, tag, weight string) ([]*queuedSound, error) {
+	candidates, err := pickCandidates(tag)
+	if err != nil {
+		return nil, retryableError(codeStorage, "Unable to list sounds", err)
+	}
+	if len(candidates) == 0 {
+		if tag != "" {
+			return nil, newUserError(codeNotFound, "No sounds are tagged "+tag)
+		}
+		return nil, newUserError(codeNotFound, "There aren't any sounds yet")
+	}
+
+	sounds := make([]*queuedSound, 0, n)
+	for _, name := range pickSounds(candidates, n, weight, time.Now(), randFloat) {
+		audio, err := loadSound(name)
+		if err != nil {
+			return nil, err
+		}
+		sounds = append(sounds, &queuedSound{name: name, audio: audio})
+	}
+	return sounds, nil
+}
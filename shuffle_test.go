@@ -0,0 +1,66 @@
+package judgego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickWeight(t *testing.T) {
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 1.0, pickWeight(clipMetadata{PlayCount: 5}, "", now))
+	assert.Equal(t, 6.0, pickWeight(clipMetadata{PlayCount: 5}, weightPlays, now))
+	assert.Equal(t, 1.0, pickWeight(clipMetadata{}, weightPlays, now))
+
+	assert.Equal(t, 1.0, pickWeight(clipMetadata{CreatedAt: now}, weightRecent, now))
+	assert.InDelta(t, 0.5, pickWeight(clipMetadata{CreatedAt: now.Add(-recencyHalfLife)}, weightRecent, now), 0.0001)
+	assert.Equal(t, minPickWeight, pickWeight(clipMetadata{CreatedAt: now.Add(-100 * recencyHalfLife)}, weightRecent, now))
+	assert.Equal(t, minPickWeight, pickWeight(clipMetadata{}, weightRecent, now))
+}
+
+func TestPickSounds(t *testing.T) {
+	now := time.Now()
+	candidates := []clipMetadata{
+		{Name: "airhorn", PlayCount: 0},
+		{Name: "bruh", PlayCount: 2},
+		{Name: "mail", PlayCount: 6},
+	}
+	fixed := func(v float64) func() float64 {
+		return func() float64 { return v }
+	}
+
+	// Weighted by plays the candidates take up 1/10, 3/10 and 7/10 of the range
+	assert.Equal(t, []string{"airhorn"}, pickSounds(candidates, 1, weightPlays, now, fixed(0.05)))
+	assert.Equal(t, []string{"bruh"}, pickSounds(candidates, 1, weightPlays, now, fixed(0.2)))
+	assert.Equal(t, []string{"mail"}, pickSounds(candidates, 1, weightPlays, now, fixed(0.5)))
+	assert.Equal(t, []string{"bruh"}, pickSounds(candidates, 1, "", now, fixed(0.5)))
+
+	// Every sound is picked at most once
+	assert.Equal(t, []string{"mail", "bruh", "airhorn"}, pickSounds(candidates, 5, weightPlays, now, fixed(0.99)))
+	assert.Empty(t, pickSounds(nil, 3, "", now, fixed(0.5)))
+}
+
+func TestShuffleSounds(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	encoded, err := gobEncodeOpusFrames([][]byte{{1, 2, 3}})
+	assert.Nil(t, err)
+	store.Put("airhorn", encoded.Bytes())
+	store.Put("mail", encoded.Bytes())
+	store.Put("sadtrombone", encoded.Bytes())
+	putMetadata(clipMetadata{Name: "sadtrombone", Tags: []string{"sad"}})
+
+	sounds, err := shuffleSounds(1, "sad", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "sadtrombone", queuedNames(sounds))
+	assert.Equal(t, [][]byte{{1, 2, 3}}, sounds[0].audio)
+
+	sounds, err = shuffleSounds(5, "", weightRecent)
+	assert.Nil(t, err)
+	assert.Len(t, sounds, 3)
+
+	_, err = shuffleSounds(1, "happy", "")
+	assert.Equal(t, "No sounds are tagged happy", userMessage(err))
+}
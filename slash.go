@@ -0,0 +1,292 @@
+package judgego
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxSlashDescription is the most characters Discord allows in a command or option description
+	maxSlashDescription = 100
+	// maxAutocompleteChoices is the most choices Discord will show for an autocompleted option
+	maxAutocompleteChoices = 25
+	// argsOptionName is the option commands without typed options take their arguments in
+	argsOptionName = "args"
+)
+
+// multiWordOptions are the options whose $ command takes the rest of the message, so they can
+// hold more than one word.
+var multiWordOptions = map[string]bool{argsOptionName: true, "effects": true, "tags": true, "query": true}
+
+// slashOptioner is implemented by commands that take options as a slash command. Commands that
+// don't are registered without any.
+type slashOptioner interface {
+	SlashOptions() []*discordgo.ApplicationCommandOption
+}
+
+func slashOptions(cmd Command) []*discordgo.ApplicationCommandOption {
+	if optioner, ok := cmd.(slashOptioner); ok {
+		return optioner.SlashOptions()
+	}
+	return nil
+}
+
+func stringOption(name, description string, required bool) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        name,
+		Description: truncateDescription(description),
+		Required:    required,
+	}
+}
+
+// soundOption is a required option naming an existing sound, autocompleted from the sound store.
+func soundOption(name, description string) *discordgo.ApplicationCommandOption {
+	opt := stringOption(name, description, true)
+	opt.Autocomplete = true
+	return opt
+}
+
+func integerOption(name, description string, min, max int) *discordgo.ApplicationCommandOption {
+	minValue := float64(min)
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        name,
+		Description: truncateDescription(description),
+		Required:    true,
+		MinValue:    &minValue,
+		MaxValue:    float64(max),
+	}
+}
+
+// weightOption picks how random sounds are weighted. Its choices are the --weight flag itself
+// so they drop straight into the $ command.
+func weightOption() *discordgo.ApplicationCommandOption {
+	opt := stringOption("weight", "Favor some sounds over others", false)
+	opt.Choices = []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "Often played", Value: "--weight " + weightPlays},
+		{Name: "Recently added", Value: "--weight " + weightRecent},
+	}
+	return opt
+}
+
+func attachmentOption(name, description string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionAttachment,
+		Name:        name,
+		Description: truncateDescription(description),
+		Required:    true,
+	}
+}
+
+// argsOption takes everything after the command name for commands with too many forms to type.
+func argsOption(cmd Command) *discordgo.ApplicationCommandOption {
+	usage := strings.TrimPrefix(cmd.Usage(), cmd.Name()+" ")
+	return stringOption(argsOptionName, usage, false)
+}
+
+func truncateDescription(description string) string {
+	if runes := []rune(description); len(runes) > maxSlashDescription {
+		return string(runes[:maxSlashDescription-3]) + "..."
+	}
+	return description
+}
+
+// slashCommands describes every registered command as an application command.
+func slashCommands() []*discordgo.ApplicationCommand {
+	appCmds := make([]*discordgo.ApplicationCommand, 0, len(commands))
+	for _, cmd := range commands {
+		appCmds = append(appCmds, &discordgo.ApplicationCommand{
+			Name:        cmd.Name(),
+			Description: truncateDescription(cmd.Description()),
+			Options:     slashOptions(cmd),
+		})
+	}
+	return appCmds
+}
+
+// registerSlashCommands replaces the guild's application commands with ours whenever we join
+// or reconnect to a guild.
+func registerSlashCommands(s *discordgo.Session, event *discordgo.GuildCreate) {
+	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, event.ID, slashCommands())
+	if err != nil {
+		log.Printf("Failed to register slash commands in %v: %v", event.ID, err)
+	}
+}
+
+func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		runSlashCommand(s, i.Interaction)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		autocompleteSound(s, i.Interaction)
+	}
+}
+
+// runSlashCommand resolves the slash command just like its $ equivalent. The reply is deferred
+// since sounds can take a while to rip, and ephemeral so there's nothing to clean up after.
+func runSlashCommand(s *discordgo.Session, i *discordgo.Interaction) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		log.Println("Failed to acknowledge slash command: ", err)
+		return
+	}
+
+	cmdResult := commandResult{}
+	cmd, ok := commandIndex[i.ApplicationCommandData().Name]
+	if !ok {
+		cmdResult.resp = "That command doesn't exist anymore"
+	} else {
+		var ctx *commandContext
+		ctx, err = newInteractionContext(s, i, cmd)
+		if err == nil {
+			cmd, err = cmd.Parse(ctx.message.Content)
+		}
+		if err != nil {
+			cmdResult.resp = userMessage(err)
+		} else {
+			cmdResult = resolveCommand(ctx, cmd)
+		}
+		if len(cmdResult.sounds) > 0 {
+			err = queueSounds(ctx, cmdResult.sounds)
+			if err != nil {
+				logError("Queueing "+queuedNames(cmdResult.sounds), err)
+				cmdResult.resp = userMessage(err)
+			} else {
+				recordPlays(cmdResult.sounds)
+				if cmdResult.resp == "" {
+					cmdResult.resp = "Queued " + queuedNames(cmdResult.sounds)
+				}
+			}
+		}
+	}
+
+	if cmdResult.resp == "" && cmdResult.file == nil {
+		cmdResult.resp = "Done!"
+	}
+	// The deferred reply holds the first chunk, the rest are sent as follow ups
+	chunks := splitMessage(cmdResult.resp, maxMessageLength)
+	first, rest := "", []string{}
+	if len(chunks) > 0 {
+		first, rest = chunks[0], chunks[1:]
+	}
+	edit := &discordgo.WebhookEdit{Content: &first}
+	if cmdResult.file != nil {
+		edit.Files = []*discordgo.File{cmdResult.file}
+	}
+	_, err = s.InteractionResponseEdit(i, edit)
+	if err != nil {
+		log.Println("Failed to reply to slash command: ", err)
+		return
+	}
+	for _, chunk := range rest {
+		_, err = s.FollowupMessageCreate(i, true, &discordgo.WebhookParams{Content: chunk, Flags: discordgo.MessageFlagsEphemeral})
+		if err != nil {
+			log.Println("Failed to send slash command follow up: ", err)
+			return
+		}
+	}
+}
+
+// newInteractionContext builds the context for a slash command. Commands are written against
+// messages, so the interaction is dressed up as one: its options become the content of the
+// equivalent $ command and attachment options become attachments.
+func newInteractionContext(s *discordgo.Session, i *discordgo.Interaction, cmd Command) (*commandContext, error) {
+	author := i.User
+	if i.Member != nil {
+		author = i.Member.User
+	}
+	m := &discordgo.Message{ChannelID: i.ChannelID, GuildID: i.GuildID, Author: author, Member: i.Member}
+	content, attachments, err := slashCommandText(cmd, i.ApplicationCommandData())
+	if err != nil {
+		return nil, err
+	}
+	m.Content, m.Attachments = content, attachments
+	return newMessageContext(s, m), nil
+}
+
+// slashCommandText turns the options into the text of the equivalent $ command. Options are
+// written in the order the command declares them, whatever order they were filled in. The $
+// command is positional, so options that would land in the wrong position are rejected: a
+// skipped option before a filled one, and spaces in single word options. Flags like
+// --weight plays can go anywhere.
+func slashCommandText(cmd Command, data discordgo.ApplicationCommandInteractionData) (string, []*discordgo.MessageAttachment, error) {
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range data.Options {
+		values[opt.Name] = opt
+	}
+
+	tokens := []string{commandPrefix + cmd.Name()}
+	attachments := make([]*discordgo.MessageAttachment, 0)
+	skipped := ""
+	for _, def := range slashOptions(cmd) {
+		opt, ok := values[def.Name]
+		if !ok {
+			if skipped == "" {
+				skipped = def.Name
+			}
+			continue
+		}
+		value := fmt.Sprint(opt.Value)
+		if def.Type == discordgo.ApplicationCommandOptionAttachment {
+			if data.Resolved != nil && data.Resolved.Attachments[value] != nil {
+				attachments = append(attachments, data.Resolved.Attachments[value])
+			}
+			continue
+		}
+		if strings.HasPrefix(value, "--") {
+			tokens = append(tokens, value)
+			continue
+		}
+		if skipped != "" {
+			return "", nil, invalidInput(fmt.Sprintf("Fill in %v to use %v", skipped, def.Name))
+		}
+		if !multiWordOptions[def.Name] && len(strings.Fields(value)) != 1 {
+			return "", nil, invalidInput(def.Name + " can't contain spaces")
+		}
+		tokens = append(tokens, value)
+	}
+	return strings.Join(tokens, " "), attachments, nil
+}
+
+// autocompleteSound suggests sounds for whichever sound option is being typed in.
+func autocompleteSound(s *discordgo.Session, i *discordgo.Interaction) {
+	query := ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Focused {
+			query = fmt.Sprint(opt.Value)
+		}
+	}
+	sounds, err := soundStore.List()
+	if err != nil {
+		log.Println("Failed to list sounds for autocomplete: ", err)
+	}
+
+	err = s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: soundChoices(sounds, query)},
+	})
+	if err != nil {
+		log.Println("Failed to send autocomplete choices: ", err)
+	}
+}
+
+// soundChoices returns the sounds matching the query, best match first.
+func soundChoices(sounds []string, query string) []*discordgo.ApplicationCommandOptionChoice {
+	matches := rankSounds(sounds, query)
+	if len(matches) > maxAutocompleteChoices {
+		matches = matches[:maxAutocompleteChoices]
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(matches))
+	for _, match := range matches {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: match.name, Value: match.name})
+	}
+	return choices
+}
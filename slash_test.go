@@ -0,0 +1,123 @@
+package judgego
+
+import (
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlashCommands(t *testing.T) {
+	validName := regexp.MustCompile(`^[a-z_]{1,32}$`)
+	appCmds := slashCommands()
+	assert.Equal(t, len(commands), len(appCmds))
+	for _, appCmd := range appCmds {
+		assert.Regexp(t, validName, appCmd.Name)
+		assert.True(t, utf8.RuneCountInString(appCmd.Description) <= maxSlashDescription, appCmd.Name)
+		for _, opt := range appCmd.Options {
+			assert.Regexp(t, validName, opt.Name)
+			assert.NotEmpty(t, opt.Description, appCmd.Name+" "+opt.Name)
+			assert.True(t, utf8.RuneCountInString(opt.Description) <= maxSlashDescription, appCmd.Name+" "+opt.Name)
+		}
+	}
+}
+
+func TestSlashCommandText(t *testing.T) {
+	// Options are written in declaration order, not the order they were filled in
+	txt, atts, err := slashCommandText(ripCommand{}, discordgo.ApplicationCommandInteractionData{
+		Name: "rip",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "end", Type: discordgo.ApplicationCommandOptionString, Value: "1m35s"},
+			{Name: "effects", Type: discordgo.ApplicationCommandOptionString, Value: "--normalize --reverse"},
+			{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "dethklok"},
+			{Name: "url", Type: discordgo.ApplicationCommandOptionString, Value: "https://youtu.be/abc"},
+			{Name: "start", Type: discordgo.ApplicationCommandOptionString, Value: "1m30s"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "$rip dethklok https://youtu.be/abc 1m30s 1m35s --normalize --reverse", txt)
+	assert.Empty(t, atts)
+
+	cmd, err := ripCommand{}.Parse(txt)
+	assert.Nil(t, err)
+	assert.Equal(t, "dethklok", cmd.(ripCommand).name)
+
+	att := &discordgo.MessageAttachment{ID: "9", Filename: "clip.mp3"}
+	txt, atts, err = slashCommandText(uploadCommand{}, discordgo.ApplicationCommandInteractionData{
+		Name: "upload",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "clip"},
+			{Name: "file", Type: discordgo.ApplicationCommandOptionAttachment, Value: "9"},
+		},
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Attachments: map[string]*discordgo.MessageAttachment{"9": att},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "$upload clip", txt)
+	assert.Equal(t, []*discordgo.MessageAttachment{att}, atts)
+
+	// An end without a start would be read as the start
+	_, _, err = slashCommandText(uploadCommand{}, discordgo.ApplicationCommandInteractionData{
+		Name: "upload",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "clip"},
+			{Name: "file", Type: discordgo.ApplicationCommandOptionAttachment, Value: "9"},
+			{Name: "end", Type: discordgo.ApplicationCommandOptionString, Value: "0m5s"},
+		},
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Attachments: map[string]*discordgo.MessageAttachment{"9": att},
+		},
+	})
+	assert.Equal(t, "Fill in start to use end", userMessage(err))
+
+	// A name with a space would be cut off at it
+	_, _, err = slashCommandText(renameCommand{}, discordgo.ApplicationCommandInteractionData{
+		Name: "rename",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "old_name", Type: discordgo.ApplicationCommandOptionString, Value: "foo"},
+			{Name: "new_name", Type: discordgo.ApplicationCommandOptionString, Value: "foo bar"},
+		},
+	})
+	assert.Equal(t, "new_name can't contain spaces", userMessage(err))
+
+	txt, _, err = slashCommandText(shuffleCommand{}, discordgo.ApplicationCommandInteractionData{
+		Name: "shuffle",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "weight", Type: discordgo.ApplicationCommandOptionString, Value: "--weight plays"},
+			{Name: "count", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(3)},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "$shuffle 3 --weight plays", txt)
+	cmd, err = shuffleCommand{}.Parse(txt)
+	assert.Nil(t, err)
+	assert.Equal(t, shuffleCommand{count: 3, weight: weightPlays}, cmd)
+
+	txt, _, _ = slashCommandText(queueCommand{}, discordgo.ApplicationCommandInteractionData{Name: "queue"})
+	assert.Equal(t, "$queue", txt)
+}
+
+func TestSoundChoices(t *testing.T) {
+	sounds := []string{"airhorn", "bruh", "horn", "Hornet", "sad"}
+
+	names := func(choices []*discordgo.ApplicationCommandOptionChoice) []string {
+		out := make([]string, 0, len(choices))
+		for _, choice := range choices {
+			out = append(out, choice.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"horn", "Hornet", "airhorn"}, names(soundChoices(sounds, "HORN")))
+	assert.Equal(t, []string{"horn"}, names(soundChoices(sounds, "hron")))
+	assert.Equal(t, []string{"Hornet", "airhorn", "bruh", "horn", "sad"}, names(soundChoices(sounds, "")))
+	assert.Empty(t, soundChoices(sounds, "xyz"))
+
+	many := make([]string, 0)
+	for i := 0; i < 30; i++ {
+		many = append(many, string(rune('a'+i%26))+"clip")
+	}
+	assert.Len(t, soundChoices(many, "clip"), maxAutocompleteChoices)
+}
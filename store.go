@@ -0,0 +1,133 @@
+package judgego
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned by a SoundStore when the requested sound does not exist.
+var errNotFound = errors.New("not found")
+
+// SoundStore is the persistence layer for sound clips. Implementations only deal in
+// the raw encoded bytes, decoding is left to the caller.
+type SoundStore interface {
+	Get(name string) ([]byte, error)
+	Put(name string, data []byte) error
+	List() ([]string, error)
+	Delete(name string) error
+	Exists(name string) (bool, error)
+	Stat(name string) (SoundInfo, error)
+}
+
+// SoundInfo contains the basic information a SoundStore knows about a stored sound.
+type SoundInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// newSoundStore returns the SoundStore selected by the S3_PERSISTENCE env variable.
+func newSoundStore() (SoundStore, error) {
+	if s3Persistence == "true" {
+		return newS3Store(bucketName, audioFilePrefix)
+	}
+	return newLocalStore(soundDir)
+}
+
+// localStore is a SoundStore backed by a directory on the local filesystem.
+type localStore struct {
+	dir string
+}
+
+func newLocalStore(dir string) (*localStore, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return &localStore{dir: dir}, nil
+}
+
+func (l *localStore) path(name string) string {
+	return filepath.Join(l.dir, filepath.Base(name))
+}
+
+func (l *localStore) Get(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(l.path(name))
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	return b, err
+}
+
+// Put writes to a hidden temp file first and renames it into place so readers never see a
+// partially written file.
+func (l *localStore) Put(name string, data []byte) error {
+	tmp, err := ioutil.TempFile(l.dir, "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path(name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (l *localStore) List() ([]string, error) {
+	files, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sounds := make([]string, 0)
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		sounds = append(sounds, f.Name())
+	}
+	return sounds, nil
+}
+
+func (l *localStore) Delete(name string) error {
+	err := os.Remove(l.path(name))
+	if os.IsNotExist(err) {
+		return errNotFound
+	}
+	return err
+}
+
+func (l *localStore) Exists(name string) (bool, error) {
+	_, err := l.Stat(name)
+	if err == errNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *localStore) Stat(name string) (SoundInfo, error) {
+	fi, err := os.Stat(l.path(name))
+	if os.IsNotExist(err) {
+		return SoundInfo{}, errNotFound
+	}
+	if err != nil {
+		return SoundInfo{}, err
+	}
+	return SoundInfo{Name: name, Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
@@ -0,0 +1,214 @@
+package judgego
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore is an in-memory SoundStore used to test the command layer.
+type memoryStore struct {
+	sync.RWMutex
+	m map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{m: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Get(name string) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+	b, ok := s.m[name]
+	if !ok {
+		return nil, errNotFound
+	}
+	return b, nil
+}
+
+func (s *memoryStore) Put(name string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	s.m[name] = data
+	return nil
+}
+
+func (s *memoryStore) List() ([]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	names := make([]string, 0, len(s.m))
+	for name := range s.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memoryStore) Delete(name string) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.m[name]; !ok {
+		return errNotFound
+	}
+	delete(s.m, name)
+	return nil
+}
+
+func (s *memoryStore) Exists(name string) (bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+	_, ok := s.m[name]
+	return ok, nil
+}
+
+func (s *memoryStore) Stat(name string) (SoundInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	b, ok := s.m[name]
+	if !ok {
+		return SoundInfo{}, errNotFound
+	}
+	return SoundInfo{Name: name, Size: int64(len(b)), ModTime: time.Now()}, nil
+}
+
+// useMemoryStore swaps soundStore and metadataStore out for fresh memoryStores. Call the
+// returned func to restore them.
+func useMemoryStore() (*memoryStore, func()) {
+	store := newMemoryStore()
+	oldSounds, oldMetadata := soundStore, metadataStore
+	soundStore, metadataStore = store, newMemoryStore()
+	resetMetadataCache()
+	return store, func() {
+		soundStore, metadataStore = oldSounds, oldMetadata
+		resetMetadataCache()
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "judgego")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := newLocalStore(dir)
+	assert.Nil(t, err)
+
+	_, err = store.Get("missing")
+	assert.Equal(t, errNotFound, err)
+
+	assert.Nil(t, store.Put("mail", []byte("frames")))
+	b, err := store.Get("mail")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("frames"), b)
+
+	exists, err := store.Exists("mail")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	info, err := store.Stat("mail")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), info.Size)
+
+	names, err := store.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mail"}, names)
+
+	assert.Nil(t, store.Delete("mail"))
+	assert.Equal(t, errNotFound, store.Delete("mail"))
+	exists, err = store.Exists("mail")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestPlaySoundFromStore(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	frames := [][]byte{{1, 2, 3}, {4, 5, 6}}
+	encoded, err := gobEncodeOpusFrames(frames)
+	assert.Nil(t, err)
+	store.Put("dethklok", encoded.Bytes())
+
+	name, audio, err := playSound(playCommand{"dethklok"})
+	assert.Nil(t, err)
+	assert.Equal(t, "dethklok", name)
+	assert.Equal(t, frames, audio)
+
+	name, audio, err = playSound(playCommand{"DETH"})
+	assert.Nil(t, err)
+	assert.Equal(t, "dethklok", name)
+	assert.Equal(t, frames, audio)
+
+	_, _, err = playSound(playCommand{"missing"})
+	assert.Equal(t, "No sound matches missing", userMessage(err))
+
+	store.Put("dethmetal", encoded.Bytes())
+	_, _, err = playSound(playCommand{"deth"})
+	assert.Equal(t, "Couldn't find deth. Did you mean dethklok, dethmetal?", userMessage(err))
+}
+
+func TestListSoundsFromStore(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", nil)
+	store.Put("dethklok", nil)
+
+	resp, err := listSounds(listCommand{})
+	assert.Nil(t, err)
+	assert.Equal(t, "Available Sounds: dethklok, mail", resp)
+}
+
+func TestDeleteSound(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", []byte("frames"))
+	putCache("mail", [][]byte{{1}})
+
+	assert.Nil(t, deleteSound(deleteCommand{"mail"}))
+	exists, _ := store.Exists("mail")
+	assert.False(t, exists)
+	_, cached := checkCache("mail")
+	assert.False(t, cached)
+
+	assert.NotNil(t, deleteSound(deleteCommand{"mail"}))
+}
+
+func TestRenameSound(t *testing.T) {
+	store, restore := useMemoryStore()
+	defer restore()
+	store.Put("mail", []byte("frames"))
+	store.Put("taken", []byte("other"))
+
+	assert.NotNil(t, renameSound(renameCommand{"mail", "taken"}))
+	assert.NotNil(t, renameSound(renameCommand{"missing", "new"}))
+
+	assert.Nil(t, renameSound(renameCommand{"mail", "letter"}))
+	b, err := store.Get("letter")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("frames"), b)
+	exists, _ := store.Exists("mail")
+	assert.False(t, exists)
+}
+
+func TestLocalStorePutReplaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "judgego")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := newLocalStore(dir)
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Put("mail", []byte("old frames")))
+	assert.Nil(t, store.Put("mail", []byte("new")))
+	b, err := store.Get("mail")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("new"), b)
+
+	// No temp files are left behind
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}